@@ -0,0 +1,143 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+// Package fswatch reports changes to a small set of files.
+//
+// Silver only cares about a handful of signal and config files, so rather than
+// watch whole trees we watch the parent directories with the native OS
+// notification API (inotify, kqueue, ReadDirectoryChangesW) and re-stat the
+// files of interest when something happens. A slow poll always runs as well so
+// we still work on file systems (e.g. network shares) that don't notify.
+package fswatch
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Watcher reports changes (create, modify, remove) to a set of files. The path
+// of each changed file, exactly as passed to New, is sent on Events. A watched
+// directory reports entries being added to or removed from it.
+type Watcher struct {
+	Events   chan string
+	paths    []string
+	poll     time.Duration
+	state    map[string]fileState
+	notifier notifier
+	changed  chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// notifier is implemented by the OS specific notification backends. The
+// backend signals the changed channel (non-blocking) when any of the watched
+// files may have changed.
+type notifier interface {
+	Close() error
+}
+
+// New starts watching paths. Native notification is used where available and
+// poll is the fallback/safety-net polling interval.
+func New(paths []string, poll time.Duration) *Watcher {
+	w := &Watcher{
+		Events:  make(chan string, len(paths)),
+		paths:   paths,
+		poll:    poll,
+		state:   make(map[string]fileState),
+		changed: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, p := range paths {
+		w.state[p] = stat(p)
+	}
+	if n, err := newNotifier(paths, w.changed); err == nil {
+		w.notifier = n
+	}
+	go w.run()
+	return w
+}
+
+// Native reports if native OS file notification is in use.
+func (w *Watcher) Native() bool {
+	return w.notifier != nil
+}
+
+// Close stops the watcher and releases any OS resources.
+func (w *Watcher) Close() {
+	close(w.stop)
+	<-w.done
+	if w.notifier != nil {
+		_ = w.notifier.Close()
+	}
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.changed:
+		case <-ticker.C:
+		}
+		for _, p := range w.paths {
+			s := stat(p)
+			if s == w.state[p] {
+				continue
+			}
+			w.state[p] = s
+			select {
+			case w.Events <- p:
+			case <-w.stop:
+				return
+			}
+		}
+	}
+}
+
+func stat(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// dirNames groups the watched files by parent directory. Directories are
+// watched themselves, with the name anyName.
+func dirNames(paths []string) map[string][]string {
+	dirs := make(map[string][]string)
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			dirs[p] = append(dirs[p], anyName)
+			continue
+		}
+		dir := filepath.Dir(p)
+		dirs[dir] = append(dirs[dir], filepath.Base(p))
+	}
+	return dirs
+}
+
+// anyName matches changes to any file in a watched directory
+const anyName = ""
+
+func notify(changed chan<- struct{}) {
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
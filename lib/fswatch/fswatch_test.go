@@ -0,0 +1,112 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package fswatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher_NotifiesOnCreateWithoutPolling(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	f := filepath.Join(dir, ".reload")
+	w := New([]string{f}, time.Hour)
+	defer w.Close()
+	if !w.Native() {
+		t.Skip("Native file notification not supported on this platform")
+	}
+
+	// Act
+	if err := os.WriteFile(f, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	select {
+	case p := <-w.Events:
+		if p != f {
+			t.Errorf("Expected event for %s, got %s", f, p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change event")
+	}
+}
+
+func TestWatcher_IgnoresOtherFiles(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	f := filepath.Join(dir, ".reload")
+	w := New([]string{f}, time.Hour)
+	defer w.Close()
+
+	// Act
+	if err := os.WriteFile(filepath.Join(dir, "other.log"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	select {
+	case p := <-w.Events:
+		t.Errorf("Did not expect event, got %s", p)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestWatcher_Directory_NotifiesOnNewEntry(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	w := New([]string{dir}, time.Hour)
+	defer w.Close()
+	if !w.Native() {
+		t.Skip("Native file notification not supported on this platform")
+	}
+
+	// Act - e.g. a new version directory
+	if err := os.Mkdir(filepath.Join(dir, "v2"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	select {
+	case p := <-w.Events:
+		if p != dir {
+			t.Errorf("Expected event for %s, got %s", dir, p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change event")
+	}
+}
+
+func TestWatcher_PollingFallback(t *testing.T) {
+	// Arrange - directory does not exist yet so it can't be natively watched
+	dir := filepath.Join(t.TempDir(), "later")
+	f := filepath.Join(dir, "service.conf")
+	w := New([]string{f}, 100*time.Millisecond)
+	defer w.Close()
+
+	// Act
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(f, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	select {
+	case p := <-w.Events:
+		if p != f {
+			t.Errorf("Expected event for %s, got %s", f, p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change event from polling")
+	}
+}
@@ -0,0 +1,129 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package fswatch
+
+import (
+	"syscall"
+	"time"
+)
+
+// O_EVTONLY - open for event notification only (don't block unmounts)
+const oEvtOnly = 0x8000
+
+const vnodeFlags = syscall.NOTE_WRITE | syscall.NOTE_DELETE | syscall.NOTE_RENAME |
+	syscall.NOTE_EXTEND | syscall.NOTE_ATTRIB
+
+// kqueue watches the parent directories (for create/delete/rename) and the
+// files themselves (for in-place writes). File watches are re-established as
+// files come and go.
+type kqueue struct {
+	kq      int
+	paths   []string
+	dirs    []int
+	files   map[string]int
+	changed chan<- struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newNotifier(paths []string, changed chan<- struct{}) (notifier, error) {
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		return nil, err
+	}
+	n := &kqueue{
+		kq:      kq,
+		paths:   paths,
+		files:   make(map[string]int),
+		changed: changed,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for dir := range dirNames(paths) {
+		// Directory may not exist yet. Polling will cover it.
+		if fd, err := n.add(dir); err == nil {
+			n.dirs = append(n.dirs, fd)
+		}
+	}
+	n.addFiles()
+	go n.run()
+	return n, nil
+}
+
+func (n *kqueue) add(path string) (int, error) {
+	fd, err := syscall.Open(path, oEvtOnly, 0)
+	if err != nil {
+		return -1, err
+	}
+	var ev syscall.Kevent_t
+	syscall.SetKevent(&ev, fd, syscall.EVFILT_VNODE, syscall.EV_ADD|syscall.EV_ENABLE|syscall.EV_CLEAR)
+	ev.Fflags = vnodeFlags
+	if _, err := syscall.Kevent(n.kq, []syscall.Kevent_t{ev}, nil, nil); err != nil {
+		_ = syscall.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+func (n *kqueue) addFiles() {
+	for _, p := range n.paths {
+		if _, ok := n.files[p]; ok {
+			continue
+		}
+		if fd, err := n.add(p); err == nil {
+			n.files[p] = fd
+		}
+	}
+}
+
+func (n *kqueue) run() {
+	defer close(n.done)
+	defer func() {
+		for _, fd := range n.files {
+			_ = syscall.Close(fd)
+		}
+		for _, fd := range n.dirs {
+			_ = syscall.Close(fd)
+		}
+	}()
+	events := make([]syscall.Kevent_t, 16)
+	timeout := syscall.NsecToTimespec(int64(time.Second))
+	for {
+		select {
+		case <-n.stop:
+			return
+		default:
+		}
+		count, err := syscall.Kevent(n.kq, nil, events, &timeout)
+		if err != nil && err != syscall.EINTR {
+			return
+		}
+		if count <= 0 {
+			continue
+		}
+		for _, ev := range events[:count] {
+			if ev.Fflags&(syscall.NOTE_DELETE|syscall.NOTE_RENAME) == 0 {
+				continue
+			}
+			for p, fd := range n.files {
+				if uint64(fd) == uint64(ev.Ident) {
+					_ = syscall.Close(fd)
+					delete(n.files, p)
+				}
+			}
+		}
+		n.addFiles()
+		notify(n.changed)
+	}
+}
+
+func (n *kqueue) Close() error {
+	close(n.stop)
+	<-n.done
+	return syscall.Close(n.kq)
+}
@@ -0,0 +1,84 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package fswatch
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY |
+	syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ATTRIB
+
+type inotify struct {
+	file    *os.File
+	watches map[int32]map[string]bool
+	changed chan<- struct{}
+}
+
+func newNotifier(paths []string, changed chan<- struct{}) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	n := &inotify{
+		// A non-blocking fd gives us a pollable file, so Close unblocks Read
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		watches: make(map[int32]map[string]bool),
+		changed: changed,
+	}
+	for dir, names := range dirNames(paths) {
+		wd, err := syscall.InotifyAddWatch(fd, dir, inotifyMask)
+		if err != nil {
+			// Directory may not exist yet. Polling will cover it.
+			continue
+		}
+		if n.watches[int32(wd)] == nil {
+			n.watches[int32(wd)] = make(map[string]bool)
+		}
+		for _, name := range names {
+			n.watches[int32(wd)][name] = true
+		}
+	}
+	if len(n.watches) == 0 {
+		_ = n.file.Close()
+		return nil, errors.New("no watchable directories")
+	}
+	go n.run()
+	return n, nil
+}
+
+func (n *inotify) run() {
+	buf := make([]byte, 64*1024)
+	for {
+		count, err := n.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= count; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			start := offset + syscall.SizeofInotifyEvent
+			end := start + int(event.Len)
+			if end > count {
+				break
+			}
+			name := strings.TrimRight(string(buf[start:end]), "\x00")
+			offset = end
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 || n.watches[event.Wd][name] || n.watches[event.Wd][anyName] {
+				notify(n.changed)
+			}
+		}
+	}
+}
+
+func (n *inotify) Close() error {
+	return n.file.Close()
+}
@@ -0,0 +1,15 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+//go:build !linux && !windows && !darwin
+
+package fswatch
+
+import "errors"
+
+func newNotifier(paths []string, changed chan<- struct{}) (notifier, error) {
+	return nil, errors.New("file notification not supported on this platform")
+}
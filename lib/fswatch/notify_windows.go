@@ -0,0 +1,132 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package fswatch
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const changeMask = windows.FILE_NOTIFY_CHANGE_FILE_NAME | windows.FILE_NOTIFY_CHANGE_SIZE |
+	windows.FILE_NOTIFY_CHANGE_LAST_WRITE | windows.FILE_NOTIFY_CHANGE_CREATION
+
+type dirWatch struct {
+	handle     windows.Handle
+	names      map[string]bool
+	overlapped windows.Overlapped // In use by the pending read, so must not move
+}
+
+type readDirectoryChanges struct {
+	dirs    []*dirWatch
+	changed chan<- struct{}
+	stop    windows.Handle // Event set by Close
+	running sync.WaitGroup
+}
+
+func newNotifier(paths []string, changed chan<- struct{}) (notifier, error) {
+	stop, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	n := &readDirectoryChanges{changed: changed, stop: stop}
+	for dir, names := range dirNames(paths) {
+		p, err := windows.UTF16PtrFromString(dir)
+		if err != nil {
+			continue
+		}
+		h, err := windows.CreateFile(p,
+			windows.FILE_LIST_DIRECTORY,
+			windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+			nil,
+			windows.OPEN_EXISTING,
+			windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OVERLAPPED,
+			0)
+		if err != nil {
+			// Directory may not exist yet. Polling will cover it.
+			continue
+		}
+		event, err := windows.CreateEvent(nil, 1, 0, nil)
+		if err != nil {
+			_ = windows.CloseHandle(h)
+			continue
+		}
+		dw := &dirWatch{handle: h, names: make(map[string]bool)}
+		dw.overlapped.HEvent = event
+		for _, name := range names {
+			dw.names[strings.ToLower(name)] = true
+		}
+		n.dirs = append(n.dirs, dw)
+	}
+	if len(n.dirs) == 0 {
+		_ = windows.CloseHandle(stop)
+		return nil, errors.New("no watchable directories")
+	}
+	n.running.Add(len(n.dirs))
+	for _, dw := range n.dirs {
+		go n.run(dw)
+	}
+	return n, nil
+}
+
+func (n *readDirectoryChanges) run(dw *dirWatch) {
+	defer n.running.Done()
+	// FILE_NOTIFY_INFORMATION entries must be DWORD aligned
+	buf := make([]uint32, 16*1024)
+	for {
+		_ = windows.ResetEvent(dw.overlapped.HEvent)
+		err := windows.ReadDirectoryChanges(dw.handle, (*byte)(unsafe.Pointer(&buf[0])),
+			uint32(len(buf)*4), false, changeMask, nil, &dw.overlapped, 0)
+		if err != nil && err != windows.ERROR_IO_PENDING {
+			return
+		}
+		wait, err := windows.WaitForMultipleObjects([]windows.Handle{dw.overlapped.HEvent, n.stop}, false, windows.INFINITE)
+		var count uint32
+		if err != nil || wait != windows.WAIT_OBJECT_0 {
+			// Closing. The read must finish before its buffer is released.
+			_ = windows.CancelIoEx(dw.handle, &dw.overlapped)
+			_ = windows.GetOverlappedResult(dw.handle, &dw.overlapped, &count, true)
+			return
+		}
+		if err := windows.GetOverlappedResult(dw.handle, &dw.overlapped, &count, false); err != nil {
+			return
+		}
+		if count == 0 {
+			// Buffer overflow - we don't know what changed
+			notify(n.changed)
+			continue
+		}
+		for offset := uint32(0); ; {
+			info := (*windows.FileNotifyInformation)(unsafe.Pointer(uintptr(unsafe.Pointer(&buf[0])) + uintptr(offset)))
+			nameLen := info.FileNameLength / 2
+			name := windows.UTF16ToString(unsafe.Slice(&info.FileName, nameLen))
+			if dw.names[strings.ToLower(name)] || dw.names[anyName] {
+				notify(n.changed)
+			}
+			if info.NextEntryOffset == 0 {
+				break
+			}
+			offset += info.NextEntryOffset
+		}
+	}
+}
+
+// Close stops the reads and waits for them to finish before closing the
+// handles, so nothing is left behind when watchers are recreated.
+func (n *readDirectoryChanges) Close() error {
+	_ = windows.SetEvent(n.stop)
+	n.running.Wait()
+	for _, dw := range n.dirs {
+		_ = windows.CloseHandle(dw.overlapped.HEvent)
+		_ = windows.CloseHandle(dw.handle)
+	}
+	return windows.CloseHandle(n.stop)
+}
@@ -0,0 +1,98 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+// journaldWriter sends log records to the systemd journal using its native
+// protocol, so the service name and stream are available as journal fields
+// (e.g. journalctl SERVICE=my-service STREAM=stderr).
+type journaldWriter struct {
+	sync.Mutex
+	tag  string
+	conn net.Conn
+}
+
+func newJournaldWriter(tag string) (*journaldWriter, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("journald is only available on Linux")
+	}
+	return &journaldWriter{tag: tag}, nil
+}
+
+func (jw *journaldWriter) Write(p []byte) (int, error) {
+	return writeLines(jw, p)
+}
+
+func (jw *journaldWriter) WriteRecord(r Record) error {
+	var b bytes.Buffer
+	journalField(&b, "MESSAGE", r.Text())
+	journalField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(r.Level)))
+	journalField(&b, "SYSLOG_IDENTIFIER", jw.tag)
+	if r.Source != "" {
+		journalField(&b, "SERVICE", r.Source)
+	}
+	if r.Stream != "" {
+		journalField(&b, "STREAM", r.Stream)
+	}
+	if r.PID != 0 {
+		journalField(&b, "SERVICE_PID", strconv.Itoa(r.PID))
+	}
+
+	jw.Lock()
+	defer jw.Unlock()
+	var err error
+	if jw.conn == nil {
+		if jw.conn, err = net.Dial("unixgram", journaldSocket); err != nil {
+			return err
+		}
+	}
+	if _, err = jw.conn.Write(b.Bytes()); err != nil {
+		jw.conn.Close()
+		jw.conn = nil
+	}
+	return err
+}
+
+// journalField appends a field in the journal's native format. Values
+// containing newlines use the binary length-prefixed form.
+func journalField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+func (jw *journaldWriter) Close() error {
+	jw.Lock()
+	defer jw.Unlock()
+	if jw.conn == nil {
+		return nil
+	}
+	err := jw.conn.Close()
+	jw.conn = nil
+	return err
+}
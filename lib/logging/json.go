@@ -0,0 +1,66 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const silverSource = "silver"
+
+// jsonWriter writes one JSON object per line. Plain text written via a
+// log.Logger is treated as a message from Silver itself.
+type jsonWriter struct {
+	sync.Mutex
+	w io.Writer
+}
+
+type jsonEntry struct {
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Source    string `json:"source"`
+	Stream    string `json:"stream,omitempty"`
+	PID       int    `json:"pid"`
+	Message   string `json:"message"`
+}
+
+func (jw *jsonWriter) Write(p []byte) (int, error) {
+	return writeLines(jw, p)
+}
+
+func (jw *jsonWriter) WriteRecord(r Record) error {
+	entry := jsonEntry{
+		Timestamp: r.Time.Format(time.RFC3339Nano),
+		Level:     r.Level,
+		Source:    r.Source,
+		Stream:    r.Stream,
+		PID:       r.PID,
+		Message:   r.Message,
+	}
+	if entry.Level == "" {
+		entry.Level = LevelInfo
+	}
+	if entry.Source == "" {
+		entry.Source = silverSource
+	}
+	if entry.PID == 0 {
+		entry.PID = os.Getpid()
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	jw.Lock()
+	defer jw.Unlock()
+	_, err = jw.w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,77 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+const LevelDebug = "debug"
+
+var levelOrder = map[string]int32{
+	LevelDebug: 0,
+	LevelInfo:  1,
+	LevelWarn:  2,
+	LevelError: 3,
+}
+
+// Minimum level of Silver's own messages. Output from services and tasks is
+// always logged.
+var minLevel atomic.Int32
+
+func init() {
+	minLevel.Store(levelOrder[LevelInfo])
+}
+
+// SetLevel sets the minimum level of Silver's own messages. An empty level
+// is the default, info. It may be changed at any time (e.g. on reload).
+func SetLevel(level string) error {
+	if level == "" {
+		level = LevelInfo
+	}
+	order, ok := levelOrder[level]
+	if !ok {
+		return fmt.Errorf("unknown log level '%s'", level)
+	}
+	minLevel.Store(order)
+	return nil
+}
+
+// ValidLevel returns true if level is a known level (or empty for the default).
+func ValidLevel(level string) bool {
+	_, ok := levelOrder[level]
+	return ok || level == ""
+}
+
+// Enabled returns true if messages at level are logged.
+func Enabled(level string) bool {
+	order, ok := levelOrder[level]
+	return !ok || order >= minLevel.Load()
+}
+
+// Debugf logs a debug message from Silver, e.g. per-ping or per-run detail.
+func Debugf(l *log.Logger, format string, v ...interface{}) {
+	Output(l, Record{Level: LevelDebug, Message: fmt.Sprintf(format, v...)})
+}
+
+// Infof logs an informational message from Silver.
+func Infof(l *log.Logger, format string, v ...interface{}) {
+	Output(l, Record{Level: LevelInfo, Message: fmt.Sprintf(format, v...)})
+}
+
+// Warnf logs a warning from Silver. By convention the message starts "WARNING:".
+func Warnf(l *log.Logger, format string, v ...interface{}) {
+	Output(l, Record{Level: LevelWarn, Message: fmt.Sprintf(format, v...)})
+}
+
+// Errorf logs an error from Silver. By convention the message starts "ERROR:".
+func Errorf(l *log.Logger, format string, v ...interface{}) {
+	Output(l, Record{Level: LevelError, Message: fmt.Sprintf(format, v...)})
+}
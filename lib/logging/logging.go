@@ -21,6 +21,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"time"
@@ -32,14 +33,37 @@ const (
 	defaultFlushInterval  = 5 * time.Second
 )
 
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config describes a logger created with NewLogger.
+type Config struct {
+	File           string // Log file, "os.stdout" / "os.stderr" for the console, or a sink (see IsSink)
+	Owner          string
+	MaxSize        int64
+	MaxBackupFiles int
+	TimeFormat     string
+	Format         string // FormatText (default) or FormatJSON
+	Rotation       string // RotateDaily or RotateHourly. Empty for size only rotation
+	Compress       bool   // gzip rotated files
+	MaxAge         time.Duration
+	MaxTotalSize   int64
+	Tag            string // Identifier used by syslog and journald. Defaults to the executable name
+}
+
 var (
 	openRollingFiles      = []*rollingFile{}
+	openRollingFilesLock  sync.Mutex
+	openSinks             = map[string]sinkWriter{}
 	changeOwnerOfFileFunc func(name, owner string) error
 )
 
 // Why a wrapper - see finalizer comment below.
 type rollingFileWrapper struct {
 	*rollingFile
+	released bool // By CloseLogger or the finalizer
 }
 
 type rollingFile struct {
@@ -54,6 +78,15 @@ type rollingFile struct {
 	currentSize         int64
 	flusher             *flusher
 	registered          bool
+	refs                int // Number of loggers sharing this file
+	rotation            string
+	compress            bool
+	maxAge              time.Duration
+	maxTotalSize        int64
+	periodStart         time.Time // Start of the rotation period of the current file
+	nextRotation        time.Time // Zero if there is no time based rotation
+	maintenance         sync.WaitGroup
+	maintenanceLock     sync.Mutex
 }
 
 type flusher struct {
@@ -92,33 +125,114 @@ func (f *flusher) run(rf *rollingFile) {
 }
 
 func stopFlusher(rfw *rollingFileWrapper) {
+	openRollingFilesLock.Lock()
+	defer openRollingFilesLock.Unlock()
+	if rfw.released {
+		return
+	}
+	rfw.released = true
+	rfw.refs--
+	if rfw.refs > 0 {
+		// Still in use by another logger
+		return
+	}
 	close(rfw.flusher.stop)
+	if rfw.registered {
+		rfw.Lock()
+		rfw.bufWriter.Flush()
+		if rfw.file != nil {
+			rfw.file.Close()
+			rfw.file = nil
+		}
+		rfw.Unlock()
+		for i, rf := range openRollingFiles {
+			if rf == rfw.rollingFile {
+				openRollingFiles = append(openRollingFiles[:i], openRollingFiles[i+1:]...)
+				break
+			}
+		}
+	}
 }
 
-func newRollingFile(name string, owner string, maxSize int64, maxFiles int) (rf *rollingFile, err error) {
-	if maxSize <= 0 {
-		maxSize = defaultMaxSize
+// sharedRollingFile returns the open rolling file for conf.File, if any.
+// Loggers for the same file (e.g. a service logging to the main log, or
+// loggers recreated on reload) must share it so they don't fight over
+// rotation. The latest logger's rotation and retention settings apply.
+func sharedRollingFile(conf Config) *rollingFile {
+	abs, err := filepath.Abs(conf.File)
+	if err != nil {
+		return nil
+	}
+	openRollingFilesLock.Lock()
+	defer openRollingFilesLock.Unlock()
+	for _, rf := range openRollingFiles {
+		if rfAbs, err := filepath.Abs(rf.name); err == nil && rfAbs == abs {
+			rf.refs++
+			rf.configure(conf)
+			return rf
+		}
 	}
+	return nil
+}
+
+func newRollingFile(conf Config) (rf *rollingFile, err error) {
 	rf = &rollingFile{
-		name:           name,
-		owner:          owner,
-		maxSize:        maxSize,
-		maxBackupFiles: maxFiles,
+		name:           conf.File,
+		owner:          conf.Owner,
+		maxSize:        maxSizeOrDefault(conf.MaxSize),
+		maxBackupFiles: conf.MaxBackupFiles,
+		refs:           1,
+		rotation:       conf.Rotation,
+		compress:       conf.Compress,
+		maxAge:         conf.MaxAge,
+		maxTotalSize:   conf.MaxTotalSize,
 		flusher: &flusher{
 			interval: defaultFlushInterval,
 			stop:     make(chan struct{}),
 		},
 	}
 	err = rf.open()
+	if err == nil && rf.datedBackups() {
+		// Apply retention (and finish any interrupted compression) from last time
+		rf.startMaintenance()
+	}
 	go rf.flusher.run(rf)
 	return
 }
 
+func maxSizeOrDefault(maxSize int64) int64 {
+	if maxSize <= 0 {
+		return defaultMaxSize
+	}
+	return maxSize
+}
+
+// configure applies new rotation and retention settings to an open file.
+func (rf *rollingFile) configure(conf Config) {
+	rf.Lock()
+	defer rf.Unlock()
+	changed := rf.maxBackupFiles != conf.MaxBackupFiles || rf.compress != conf.Compress ||
+		rf.maxAge != conf.MaxAge || rf.maxTotalSize != conf.MaxTotalSize
+	rf.maxSize = maxSizeOrDefault(conf.MaxSize)
+	rf.maxBackupFiles = conf.MaxBackupFiles
+	rf.compress = conf.Compress
+	rf.maxAge = conf.MaxAge
+	rf.maxTotalSize = conf.MaxTotalSize
+	if rf.rotation != conf.Rotation {
+		rf.rotation = conf.Rotation
+		rf.periodStart, rf.nextRotation = rotationPeriod(rf.periodStart, rf.rotation)
+	}
+	if changed && rf.datedBackups() {
+		// Apply the new retention now rather than at the next rotation
+		rf.startMaintenance()
+	}
+}
+
 func (rf *rollingFile) Write(p []byte) (n int, err error) {
 	rf.Lock()
 	defer rf.Unlock()
 
-	if rf.currentSize+int64(len(p)) >= rf.maxSize {
+	if rf.currentSize+int64(len(p)) >= rf.maxSize || rf.rotationDue() {
 		err = rf.roll()
 		if err != nil {
 			return
@@ -127,6 +241,7 @@ func (rf *rollingFile) Write(p []byte) (n int, err error) {
 	n, err = rf.bufWriter.Write(p)
 	rf.currentSize += int64(n)
 	rf.bytesSinceLastFlush += int64(n)
+	addBytesWritten(rf.name, n)
 	return
 }
 
@@ -151,8 +266,16 @@ func (rf *rollingFile) open() error {
 		return err
 	}
 	rf.currentSize = finfo.Size()
+	// Existing content belongs to the period it was last written in
+	start := timeNow()
+	if rf.currentSize > 0 {
+		start = finfo.ModTime()
+	}
+	rf.periodStart, rf.nextRotation = rotationPeriod(start, rf.rotation)
 	if !rf.registered {
+		openRollingFilesLock.Lock()
 		openRollingFiles = append(openRollingFiles, rf)
+		openRollingFilesLock.Unlock()
 		rf.registered = true
 	}
 	return nil
@@ -167,6 +290,11 @@ func (rf *rollingFile) roll() error {
 	rf.file.Close()
 	rf.file = nil
 
+	if rf.datedBackups() {
+		rf.rollDated(name)
+		return rf.open()
+	}
+
 	// Start from the last backup file and move everything back by 1 step
 	for i := rf.maxBackupFiles; i > 0; i-- {
 		var renameFrom, renameTo string
@@ -207,37 +335,109 @@ func NewFileLogger(file string, owner string, timeformat string) (logger *log.Lo
 
 // NewFileLoggerWithMaxSize implements a rolling logger with a set size
 func NewFileLoggerWithMaxSize(file string, owner string, maxSize int64, maxBackupFiles int, timeformat string) (logger *log.Logger) {
-	rf, err := newRollingFile(file, owner, maxSize, maxBackupFiles)
-	// This trick ensures that the flusher goroutine does not keep
-	// the returned wrapper object from being garbage collected. When it is
-	// garbage collected, the finalizer stops the janitor goroutine, after
-	// which rw can be collected.
-	rfWrapper := &rollingFileWrapper{rf}
-	runtime.SetFinalizer(rfWrapper, stopFlusher)
-	if err == nil {
-		var writer io.Writer = rfWrapper
-		flags := log.Ldate | log.Ltime
-		if timeformat != "" {
-			writer = logWriter{Writer: rfWrapper, timeformat: timeformat}
-			flags = 0
+	return NewLogger(Config{
+		File:           file,
+		Owner:          owner,
+		MaxSize:        maxSize,
+		MaxBackupFiles: maxBackupFiles,
+		TimeFormat:     timeformat,
+	})
+}
+
+// NewLogger implements a logger to either a rolling log file or the console
+// (os.stdout or os.stderr) in either text or JSON format.
+func NewLogger(conf Config) (logger *log.Logger) {
+	var writer io.Writer
+	switch {
+	case conf.File == "os.stdout":
+		writer = os.Stdout
+	case conf.File == "os.stderr":
+		writer = os.Stderr
+	case IsSink(conf.File):
+		sink, err := sharedSink(conf.File, conf.Tag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: Unable to set up log %s: %v\n", conf.File, err)
+			return NewNilLogger()
+		}
+		// Sinks take the structured record, and add their own timestamps
+		return log.New(sink, "", 0)
+	default:
+		if rf := sharedRollingFile(conf); rf != nil {
+			rfWrapper := &rollingFileWrapper{rollingFile: rf}
+			runtime.SetFinalizer(rfWrapper, stopFlusher)
+			writer = rfWrapper
+			break
+		}
+		rf, err := newRollingFile(conf)
+		// This trick ensures that the flusher goroutine does not keep
+		// the returned wrapper object from being garbage collected. When it is
+		// garbage collected, the finalizer stops the janitor goroutine, after
+		// which rw can be collected.
+		rfWrapper := &rollingFileWrapper{rollingFile: rf}
+		runtime.SetFinalizer(rfWrapper, stopFlusher)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: Unable to set up log file: %v\n", err)
+			return NewNilLogger()
 		}
-		logger = log.New(writer, "", flags)
-	} else {
-		fmt.Fprintf(os.Stderr, "WARNING: Unable to set up log file: %v\n", err)
-		logger = NewNilLogger()
+		writer = rfWrapper
+	}
+	return newFormattedLogger(writer, conf.Format, conf.TimeFormat)
+}
+
+func newFormattedLogger(writer io.Writer, format string, timeformat string) *log.Logger {
+	if format == FormatJSON {
+		// JSON entries carry their own timestamp
+		return log.New(&jsonWriter{w: writer}, "", 0)
+	}
+	flags := log.Ldate | log.Ltime
+	if timeformat != "" {
+		writer = logWriter{Writer: writer, timeformat: timeformat}
+		flags = 0
 	}
-	return logger
+	return log.New(writer, "", flags)
+}
+
+// CloseLogger releases the log file of a logger created by NewLogger, closing
+// it if no other logger shares it. Rather than waiting for the garbage
+// collector, loggers that are replaced (e.g. on reload) should be closed so the
+// file is closed promptly. The logger must not be used afterwards.
+func CloseLogger(logger *log.Logger) {
+	if rfw := rollingFileOf(logger.Writer()); rfw != nil {
+		runtime.SetFinalizer(rfw, nil)
+		stopFlusher(rfw)
+	}
+}
+
+func rollingFileOf(w io.Writer) *rollingFileWrapper {
+	switch w := w.(type) {
+	case *rollingFileWrapper:
+		return w
+	case logWriter:
+		return rollingFileOf(w.Writer)
+	case *jsonWriter:
+		return rollingFileOf(w.w)
+	}
+	return nil
 }
 
 // CloseAllOpenFileLoggers is a convenience method for tests
 func CloseAllOpenFileLoggers() {
+	openRollingFilesLock.Lock()
+	defer openRollingFilesLock.Unlock()
 	for _, rf := range openRollingFiles {
+		rf.maintenance.Wait()
+		rf.Lock() // The flusher may still be running
 		rf.bufWriter.Flush()
 		if rf.file != nil {
 			rf.file.Close()
 		}
+		rf.Unlock()
 	}
 	openRollingFiles = []*rollingFile{}
+	for key, sink := range openSinks {
+		sink.Close()
+		delete(openSinks, key)
+	}
 }
 
 // NewNilLogger is a logger noop/discade implementation
@@ -247,24 +447,10 @@ func NewNilLogger() *log.Logger {
 
 // NewConsoleErrorLogger is a basic logger to Stderr
 func NewConsoleErrorLogger(timeformat string) (logger *log.Logger) {
-	var writer io.Writer = os.Stderr
-	flags := log.Ldate | log.Ltime
-	if timeformat != "" {
-		writer = logWriter{Writer: os.Stderr, timeformat: timeformat}
-		flags = 0
-	}
-	logger = log.New(writer, "", flags)
-	return logger
+	return newFormattedLogger(os.Stderr, FormatText, timeformat)
 }
 
 // NewConsoleLogger is a basic logger to Stdout
 func NewConsoleLogger(timeformat string) (logger *log.Logger) {
-	var writer io.Writer = os.Stdout
-	flags := log.Ldate | log.Ltime
-	if timeformat != "" {
-		writer = logWriter{Writer: os.Stdout, timeformat: timeformat}
-		flags = 0
-	}
-	logger = log.New(writer, "", flags)
-	return logger
+	return newFormattedLogger(os.Stdout, FormatText, timeformat)
 }
@@ -1,10 +1,19 @@
 package logging
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -139,3 +148,399 @@ func TestRollingLogFlush_IsFlushed(t *testing.T) {
 		t.Errorf("Expected 'x' in file. It did not flush in time")
 	}
 }
+
+func TestJSONLogging(t *testing.T) {
+	lname := fmt.Sprintf("%s/test-json-log-%d.log", os.TempDir(), time.Now().Unix())
+	logger := NewLogger(Config{File: lname, Format: FormatJSON})
+	defer func() {
+		os.Remove(lname)
+	}()
+
+	logger.Printf("ERROR: Something went wrong")
+	Output(logger, Record{Level: LevelInfo, Source: "my-server", Stream: "stdout", PID: 1234, Message: `Hello "World"`})
+	CloseAllOpenFileLoggers()
+
+	output, err := os.ReadFile(lname)
+	if err != nil {
+		t.Fatalf("Unable to read file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %q", len(lines), output)
+	}
+
+	var silverEntry, serviceEntry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &silverEntry); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &serviceEntry); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", lines[1], err)
+	}
+
+	if silverEntry["source"] != "silver" || silverEntry["level"] != "error" {
+		t.Errorf("Unexpected Silver entry: %v", silverEntry)
+	}
+	if int(silverEntry["pid"].(float64)) != os.Getpid() {
+		t.Errorf("Expected Silver's PID, got %v", silverEntry["pid"])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, silverEntry["timestamp"].(string)); err != nil {
+		t.Errorf("Expected RFC3339 timestamp: %v", err)
+	}
+	if serviceEntry["source"] != "my-server" || serviceEntry["stream"] != "stdout" ||
+		serviceEntry["pid"].(float64) != 1234 || serviceEntry["message"] != `Hello "World"` {
+		t.Errorf("Unexpected service entry: %v", serviceEntry)
+	}
+}
+
+func TestSharedLogFile(t *testing.T) {
+	lname := fmt.Sprintf("%s/test-shared-log-%d.log", os.TempDir(), time.Now().Unix())
+	defer func() {
+		os.Remove(lname)
+		os.Remove(lname + ".1")
+	}()
+
+	// e.g. a service logging to the main log, or loggers recreated on reload
+	first := NewFileLoggerWithMaxSize(lname, "", 1024, 1, "")
+	second := NewFileLoggerWithMaxSize(lname, "", 1024, 1, "")
+
+	if len(openRollingFiles) != 1 {
+		t.Errorf("Expected loggers to share one rolling file, got %d", len(openRollingFiles))
+	}
+	first.Print("first")
+	second.Print("second")
+	CloseAllOpenFileLoggers()
+
+	output, err := os.ReadFile(lname)
+	if err != nil {
+		t.Fatalf("Unable to read file: %v", err)
+	}
+	if !strings.Contains(string(output), "first") || !strings.Contains(string(output), "second") {
+		t.Errorf("Expected output from both loggers, got %q", output)
+	}
+}
+
+func TestSharedLogFile_ReloadAppliesNewSettings(t *testing.T) {
+	dir := t.TempDir()
+	lname := dir + "/test-reload.log"
+	// A backup beyond the max age set on reload
+	old := lname + ".2026-01-01"
+	if err := os.WriteFile(old, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldTime := time.Now().Add(-40 * 24 * time.Hour)
+	os.Chtimes(old, oldTime, oldTime)
+
+	before := NewLogger(Config{File: lname, MaxSize: 1024 * 1024, MaxBackupFiles: 1})
+	before.Print("before reload")
+	// The logger recreated on reload, while the first is still open
+	after := NewLogger(Config{File: lname, MaxSize: 1024, MaxAge: 30 * 24 * time.Hour})
+	for i := 0; i < 40; i++ {
+		after.Printf("Line %d, filling up the log so it is rotated by size", i)
+	}
+	CloseAllOpenFileLoggers()
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("Expected the backup older than the new max age to be removed")
+	}
+	backups, _ := filepath.Glob(lname + ".20*")
+	if len(backups) < 2 {
+		t.Errorf("Expected rotation at the new max size, got backups %v", backups)
+	}
+	if info, err := os.Stat(lname); err != nil || info.Size() >= 1024 {
+		t.Errorf("Expected the current log to be smaller than the new max size: %v", err)
+	}
+}
+
+func TestCloseLogger_ClosesFileWhenNoLongerShared(t *testing.T) {
+	lname := t.TempDir() + "/test-close.log"
+	first := NewLogger(Config{File: lname})
+	second := NewLogger(Config{File: lname, Format: FormatJSON})
+
+	first.Print("first")
+	CloseLogger(first)
+	CloseLogger(first) // Must not release the other logger's share
+	if len(openRollingFiles) != 1 {
+		t.Fatalf("Expected the file to stay open for the other logger")
+	}
+	second.Print("second")
+	CloseLogger(second)
+
+	if len(openRollingFiles) != 0 {
+		t.Errorf("Expected the file to be closed once no logger uses it")
+	}
+	output, err := os.ReadFile(lname)
+	if err != nil {
+		t.Fatalf("Unable to read file: %v", err)
+	}
+	if !strings.Contains(string(output), "first") || !strings.Contains(string(output), "second") {
+		t.Errorf("Expected output from both loggers to be flushed, got %q", output)
+	}
+}
+
+func TestTimeRotation_Hourly(t *testing.T) {
+	dir := t.TempDir()
+	lname := dir + "/test-hourly.log"
+	// Also read by the maintenance goroutine
+	var clock sync.Mutex
+	now := time.Date(2026, 3, 1, 10, 30, 0, 0, time.Local)
+	timeNow = func() time.Time {
+		clock.Lock()
+		defer clock.Unlock()
+		return now
+	}
+	defer func() { timeNow = time.Now }()
+
+	logger := NewLogger(Config{File: lname, Rotation: RotateHourly})
+	logger.Print("first hour")
+	clock.Lock()
+	now = now.Add(time.Hour)
+	clock.Unlock()
+	logger.Print("second hour")
+	CloseAllOpenFileLoggers()
+
+	backup := lname + ".2026-03-01-10"
+	output, err := os.ReadFile(backup)
+	if err != nil {
+		t.Fatalf("Expected dated backup %s: %v", backup, err)
+	}
+	if !strings.Contains(string(output), "first hour") || strings.Contains(string(output), "second hour") {
+		t.Errorf("Unexpected backup content: %q", output)
+	}
+	output, _ = os.ReadFile(lname)
+	if !strings.Contains(string(output), "second hour") {
+		t.Errorf("Expected new hour in current log, got: %q", output)
+	}
+}
+
+func TestTimeRotation_CompressAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	lname := dir + "/test-retention.log"
+	// An old backup beyond the max age
+	old := lname + ".2026-01-01"
+	if err := os.WriteFile(old, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldTime := time.Now().Add(-40 * 24 * time.Hour)
+	os.Chtimes(old, oldTime, oldTime)
+
+	logger := NewLogger(Config{
+		File:     lname,
+		MaxSize:  1024,
+		Compress: true,
+		MaxAge:   30 * 24 * time.Hour,
+	})
+	for i := 0; i < 40; i++ {
+		logger.Printf("Line %d, filling up the log so it is rotated by size", i)
+	}
+	CloseAllOpenFileLoggers()
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("Expected old backup to be removed")
+	}
+	compressed, _ := filepath.Glob(lname + ".*.gz")
+	if len(compressed) == 0 {
+		t.Fatalf("Expected compressed backups")
+	}
+	uncompressed, _ := filepath.Glob(lname + ".20*[0-9]")
+	if len(uncompressed) != 0 {
+		t.Errorf("Expected all backups to be compressed, found %v", uncompressed)
+	}
+	f, err := os.Open(compressed[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(zr)
+	if !strings.Contains(string(content), "filling up the log") {
+		t.Errorf("Unexpected compressed content: %q", content)
+	}
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	defer CloseAllOpenFileLoggers()
+
+	logger := NewLogger(Config{File: "syslog://" + conn.LocalAddr().String(), Tag: "my-app"})
+	Output(logger, Record{Level: LevelError, Source: "my-service", Stream: "stderr", PID: 1234, Message: "Boom"})
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected syslog message: %v", err)
+	}
+	// <daemon.err>1 TIMESTAMP HOST APP PID MSGID - MSG
+	expected := regexp.MustCompile(`^<27>1 \S+ \S+ my-app 1234 my-service - my-service: STDERR\|Boom$`)
+	if !expected.Match(buf[:n]) {
+		t.Errorf("Unexpected syslog message: %q", buf[:n])
+	}
+}
+
+func TestSyslogSink_TCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	defer CloseAllOpenFileLoggers()
+
+	logger := NewLogger(Config{File: "syslog+tcp://" + ln.Addr().String(), Tag: "my-app"})
+	logger.Print("WARNING: Something odd")
+
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(c)
+	count, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil {
+		t.Fatalf("Expected octet count: %v", err)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(msg), "<28>1 ") || !strings.HasSuffix(string(msg), " - - WARNING: Something odd") {
+		t.Errorf("Unexpected syslog message: %q", msg)
+	}
+}
+
+func TestJournalField(t *testing.T) {
+	var b bytes.Buffer
+	journalField(&b, "SERVICE", "my-service")
+	journalField(&b, "MESSAGE", "two\nlines")
+
+	expected := "SERVICE=my-service\nMESSAGE\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\n"
+	if b.String() != expected {
+		t.Errorf("Unexpected journal fields: %q", b.String())
+	}
+}
+
+func TestLogLevel_FiltersSilverMessagesOnly(t *testing.T) {
+	lname := fmt.Sprintf("%s/test-level-log-%d.log", os.TempDir(), time.Now().Unix())
+	defer os.Remove(lname)
+	defer SetLevel(LevelInfo)
+
+	if err := SetLevel(LevelWarn); err != nil {
+		t.Fatal(err)
+	}
+	logger := NewFileLogger(lname, "", "")
+	Debugf(logger, "debug message")
+	Infof(logger, "info message")
+	Warnf(logger, "WARNING: warn message")
+	Output(logger, Record{Level: LevelInfo, Source: "my-service", Stream: "stdout", Message: "service output"})
+	CloseAllOpenFileLoggers()
+
+	output, err := os.ReadFile(lname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(output), "debug message") || strings.Contains(string(output), "info message") {
+		t.Errorf("Expected debug and info messages to be filtered, got: %s", output)
+	}
+	if !strings.Contains(string(output), "warn message") || !strings.Contains(string(output), "service output") {
+		t.Errorf("Expected warning and service output, got: %s", output)
+	}
+	if SetLevel("verbose") == nil {
+		t.Errorf("Expected error for unknown level")
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r, err := NewRedactor([]string{`password=(\S+)`, `\b\d{4}-\d{4}-\d{4}-\d{4}\b`}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := r.Redact("Connecting with user=bob password=s3cret card 1234-5678-9012-3456")
+	expected := "Connecting with user=bob password=[REDACTED] card [REDACTED]"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if _, err := NewRedactor([]string{"("}, ""); err == nil {
+		t.Errorf("Expected error for an invalid pattern")
+	}
+}
+
+func TestRedaction_SilverMessages(t *testing.T) {
+	lname := fmt.Sprintf("%s/test-redact-log-%d.log", os.TempDir(), time.Now().Unix())
+	defer os.Remove(lname)
+	r, _ := NewRedactor([]string{`token=(\w+)`}, "***")
+	SetRedactor(r)
+	defer SetRedactor(nil)
+
+	logger := NewLogger(Config{File: lname, Format: FormatJSON})
+	Infof(logger, "Proxy set to http://proxy?token=abc123")
+	CloseAllOpenFileLoggers()
+
+	output, _ := os.ReadFile(lname)
+	if strings.Contains(string(output), "abc123") || !strings.Contains(string(output), "token=***") {
+		t.Errorf("Expected the token to be redacted, got: %s", output)
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	rb := NewRingBuffer(3)
+	records, cancel := rb.Subscribe()
+	defer cancel()
+	for i := 1; i <= 5; i++ {
+		rb.Add(Record{Message: strconv.Itoa(i)})
+	}
+
+	var last []string
+	for _, r := range rb.Last(0) {
+		last = append(last, r.Message)
+	}
+	if strings.Join(last, ",") != "3,4,5" {
+		t.Errorf("Expected the last 3 records, got %v", last)
+	}
+	if got := rb.Last(2); len(got) != 2 || got[0].Message != "4" {
+		t.Errorf("Expected the last 2 records, got %v", got)
+	}
+	if r := <-records; r.Message != "1" {
+		t.Errorf("Expected subscriber to receive records as added, got %v", r.Message)
+	}
+}
+
+func TestRingBuffer_Resize(t *testing.T) {
+	rb := NewRingBuffer(5)
+	for i := 1; i <= 4; i++ {
+		rb.Add(Record{Message: strconv.Itoa(i)})
+	}
+
+	messages := func() string {
+		var last []string
+		for _, r := range rb.Last(0) {
+			last = append(last, r.Message)
+		}
+		return strings.Join(last, ",")
+	}
+	rb.Resize(2)
+	if got := messages(); got != "3,4" {
+		t.Errorf("Expected shrinking to keep the most recent records, got %s", got)
+	}
+	rb.Resize(4)
+	rb.Add(Record{Message: "5"})
+	if got := messages(); got != "3,4,5" {
+		t.Errorf("Expected growing to keep the records and make room for more, got %s", got)
+	}
+	rb.Add(Record{Message: "6"})
+	rb.Add(Record{Message: "7"})
+	if got := messages(); got != "4,5,6,7" {
+		t.Errorf("Expected the resized buffer to wrap, got %s", got)
+	}
+}
@@ -0,0 +1,104 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// Record is a single log message along with where it came from.
+type Record struct {
+	Time    time.Time
+	Level   string
+	Source  string // Service or task name. Empty for Silver's own messages.
+	Stream  string // "stdout" or "stderr" for output from a service or task.
+	PID     int
+	Message string
+}
+
+// RecordWriter is implemented by log writers that can output the structured
+// record rather than a line of text (e.g. JSON output).
+type RecordWriter interface {
+	WriteRecord(r Record) error
+}
+
+// Output writes the record to the logger if its level is enabled. If the
+// logger's writer is a RecordWriter the full record is written, otherwise
+// it's logged as text.
+func Output(l *log.Logger, r Record) {
+	if l == nil {
+		return
+	}
+	if r.Stream == "" {
+		if r.Level == "" {
+			r.Level = levelFromText(r.Message)
+		}
+		// Only Silver's own messages are filtered, never service or task output
+		if !Enabled(r.Level) {
+			return
+		}
+		// Service and task output is redacted a line at a time as it's read
+		r.Message = Redact(r.Message)
+	}
+	if rw, ok := l.Writer().(RecordWriter); ok {
+		if r.Time.IsZero() {
+			r.Time = time.Now()
+		}
+		_ = rw.WriteRecord(r)
+		return
+	}
+	l.Print(r.Text())
+}
+
+// Text formats the record as Silver's traditional text log line (less the
+// timestamp). e.g. "my-service: STDOUT|Hello World"
+func (r Record) Text() string {
+	switch {
+	case r.Stream != "":
+		return fmt.Sprintf("%s: %s|%s", r.Source, strings.ToUpper(r.Stream), r.Message)
+	case r.Source != "":
+		return fmt.Sprintf("%s: %s", r.Source, r.Message)
+	default:
+		return r.Message
+	}
+}
+
+// writeLines writes plain text from a log.Logger to a RecordWriter as
+// messages from Silver itself, one record per line.
+func writeLines(rw RecordWriter, p []byte) (int, error) {
+	now := time.Now()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		r := Record{Time: now, Level: levelFromText(line), Message: Redact(line)}
+		if err := rw.WriteRecord(r); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// levelFromText determines the level of a plain text message using the
+// "ERROR:" and "WARNING:" prefix convention.
+func levelFromText(msg string) string {
+	switch {
+	case strings.HasPrefix(msg, "ERROR"):
+		return LevelError
+	case strings.HasPrefix(msg, "WARNING"):
+		return LevelWarn
+	default:
+		return LevelInfo
+	}
+}
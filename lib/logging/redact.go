@@ -0,0 +1,93 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+)
+
+const DefaultRedactReplacement = "[REDACTED]"
+
+// Redactor replaces sensitive text (passwords, tokens, etc.) in log messages.
+// If a pattern has capture groups only the captured text is replaced, so
+// "password=(\S+)" logs as "password=[REDACTED]". Otherwise the whole match
+// is replaced.
+type Redactor struct {
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+var redactor atomic.Pointer[Redactor]
+
+// NewRedactor compiles the redaction patterns.
+func NewRedactor(patterns []string, replacement string) (*Redactor, error) {
+	if replacement == "" {
+		replacement = DefaultRedactReplacement
+	}
+	r := &Redactor{replacement: replacement}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern '%s': %v", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// SetRedactor sets the redactor applied to all log output. nil disables
+// redaction. It may be changed at any time (e.g. on reload).
+func SetRedactor(r *Redactor) {
+	redactor.Store(r)
+}
+
+// Redact applies the current redactor to msg.
+func Redact(msg string) string {
+	if r := redactor.Load(); r != nil {
+		return r.Redact(msg)
+	}
+	return msg
+}
+
+// Redact replaces any sensitive text in msg.
+func (r *Redactor) Redact(msg string) string {
+	for _, re := range r.patterns {
+		if re.NumSubexp() == 0 {
+			msg = re.ReplaceAllLiteralString(msg, r.replacement)
+			continue
+		}
+		msg = replaceGroups(re, msg, r.replacement)
+	}
+	return msg
+}
+
+// replaceGroups replaces the text of each capture group in all matches.
+func replaceGroups(re *regexp.Regexp, msg, replacement string) string {
+	matches := re.FindAllStringSubmatchIndex(msg, -1)
+	if matches == nil {
+		return msg
+	}
+	var out []byte
+	last := 0
+	for _, m := range matches {
+		for g := 2; g+1 < len(m); g += 2 {
+			start, end := m[g], m[g+1]
+			if start < last || start < 0 {
+				// Group didn't participate, or is nested in one already replaced
+				continue
+			}
+			out = append(out, msg[last:start]...)
+			out = append(out, replacement...)
+			last = end
+		}
+	}
+	out = append(out, msg[last:]...)
+	return string(out)
+}
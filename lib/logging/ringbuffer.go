@@ -0,0 +1,104 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import "sync"
+
+// Records are dropped for a subscriber that falls this far behind
+const subscriberBacklog = 1000
+
+// RingBuffer keeps the most recent records in memory, e.g. the last lines of
+// a service's output, and passes new records on to any subscribers.
+type RingBuffer struct {
+	sync.Mutex
+	records     []Record
+	next        int
+	full        bool
+	subscribers map[chan Record]struct{}
+}
+
+// NewRingBuffer creates a buffer holding up to size records.
+func NewRingBuffer(size int) *RingBuffer {
+	if size <= 0 {
+		size = 1
+	}
+	return &RingBuffer{
+		records:     make([]Record, size),
+		subscribers: make(map[chan Record]struct{}),
+	}
+}
+
+func (rb *RingBuffer) Add(r Record) {
+	rb.Lock()
+	defer rb.Unlock()
+	rb.records[rb.next] = r
+	rb.next = (rb.next + 1) % len(rb.records)
+	if rb.next == 0 {
+		rb.full = true
+	}
+	for c := range rb.subscribers {
+		select {
+		case c <- r:
+		default:
+			// Don't hold up the service's output for a slow reader
+		}
+	}
+}
+
+// Last returns up to n of the most recent records, oldest first.
+func (rb *RingBuffer) Last(n int) []Record {
+	rb.Lock()
+	defer rb.Unlock()
+	return rb.last(n)
+}
+
+// Resize changes the number of records held, keeping the most recent.
+func (rb *RingBuffer) Resize(size int) {
+	if size <= 0 {
+		size = 1
+	}
+	rb.Lock()
+	defer rb.Unlock()
+	if size == len(rb.records) {
+		return
+	}
+	last := rb.last(size)
+	rb.records = make([]Record, size)
+	copy(rb.records, last)
+	rb.next = len(last) % size
+	rb.full = len(last) == size
+}
+
+func (rb *RingBuffer) last(n int) []Record {
+	count := rb.next
+	if rb.full {
+		count = len(rb.records)
+	}
+	if n > count || n <= 0 {
+		n = count
+	}
+	last := make([]Record, 0, n)
+	for i := n; i > 0; i-- {
+		last = append(last, rb.records[(rb.next-i+len(rb.records))%len(rb.records)])
+	}
+	return last
+}
+
+// Subscribe returns a channel of records as they're added. cancel must be
+// called once done.
+func (rb *RingBuffer) Subscribe() (records <-chan Record, cancel func()) {
+	c := make(chan Record, subscriberBacklog)
+	rb.Lock()
+	rb.subscribers[c] = struct{}{}
+	rb.Unlock()
+	return c, func() {
+		rb.Lock()
+		delete(rb.subscribers, c)
+		rb.Unlock()
+	}
+}
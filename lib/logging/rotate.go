@@ -0,0 +1,213 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	RotateDaily  = "daily"
+	RotateHourly = "hourly"
+)
+
+// Overridden in tests
+var timeNow = time.Now
+
+// datedBackups is true if rotated files are named by date rather than the
+// classic numbered scheme (log.1, log.2, ...). Any of the time, compression or
+// retention options switch to dated names.
+func (rf *rollingFile) datedBackups() bool {
+	return rf.rotation != "" || rf.compress || rf.maxAge > 0 || rf.maxTotalSize > 0
+}
+
+// rotationPeriod returns the start of the period containing t and the time of
+// the next rotation (zero if there is no time based rotation).
+func rotationPeriod(t time.Time, rotation string) (start, next time.Time) {
+	switch rotation {
+	case RotateHourly:
+		start = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+		return start, start.Add(time.Hour)
+	case RotateDaily:
+		start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 0, 1)
+	default:
+		return t, time.Time{}
+	}
+}
+
+func (rf *rollingFile) rotationDue() bool {
+	return !rf.nextRotation.IsZero() && !timeNow().Before(rf.nextRotation)
+}
+
+func (rf *rollingFile) backupStamp() string {
+	if rf.rotation == RotateHourly {
+		return rf.periodStart.Format("2006-01-02-15")
+	}
+	return rf.periodStart.Format("2006-01-02")
+}
+
+// rollDated renames the current file to <name>.<date> (adding a counter if
+// the period has already been rolled due to size) and then compresses and
+// prunes old backups in the background.
+func (rf *rollingFile) rollDated(name string) {
+	base := name + "." + rf.backupStamp()
+	backup := base
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		backup = fmt.Sprintf("%s.%d", base, i)
+	}
+	if err := os.Rename(name, backup); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "ERROR: Error renaming %s to %s. %v\n", name, backup, err)
+	}
+	rf.startMaintenance()
+}
+
+// startMaintenance compresses and prunes backups in the background. It's
+// called with rf locked (or not yet shared), and as the settings may change
+// on reload, the background work uses the settings as they are now.
+func (rf *rollingFile) startMaintenance() {
+	compress := rf.compress
+	maxBackupFiles, maxAge, maxTotalSize := rf.maxBackupFiles, rf.maxAge, rf.maxTotalSize
+	rf.maintenance.Add(1)
+	go func() {
+		defer rf.maintenance.Done()
+		rf.maintenanceLock.Lock()
+		defer rf.maintenanceLock.Unlock()
+		if compress {
+			rf.compressBackups()
+		}
+		rf.pruneBackups(maxBackupFiles, maxAge, maxTotalSize)
+	}()
+}
+
+type backupFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// backups lists dated backup files of this log, newest first.
+func (rf *rollingFile) backups() []backupFile {
+	dir, base := filepath.Split(rf.name)
+	if dir == "" {
+		dir = "."
+	}
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `\.\d{4}-\d{2}-\d{2}(-\d{2})?(\.\d+)?(\.gz)?$`)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []backupFile
+	for _, e := range entries {
+		if e.IsDir() || !pattern.MatchString(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{
+			path:    filepath.Join(dir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	return files
+}
+
+func (rf *rollingFile) compressBackups() {
+	for _, b := range rf.backups() {
+		if strings.HasSuffix(b.path, ".gz") {
+			continue
+		}
+		if err := compressFile(b.path, rf.owner); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Error compressing %s. %v\n", b.path, err)
+		}
+	}
+}
+
+// pruneBackups deletes backups beyond the configured count, age or total size.
+// The total size includes the current log file.
+func (rf *rollingFile) pruneBackups(maxBackupFiles int, maxAge time.Duration, maxTotalSize int64) {
+	var total int64
+	if info, err := os.Stat(rf.name); err == nil {
+		total = info.Size()
+	}
+	now := timeNow()
+	for i, b := range rf.backups() {
+		total += b.size
+		expired := (maxBackupFiles > 0 && i >= maxBackupFiles) ||
+			(maxAge > 0 && now.Sub(b.modTime) > maxAge) ||
+			(maxTotalSize > 0 && total > maxTotalSize)
+		if !expired {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "ERROR: Error removing old log file %s. %v\n", b.path, err)
+		}
+	}
+}
+
+// compressFile gzips name to name.gz, keeping the modification time so age
+// based retention still applies, and removes the original.
+func compressFile(name, owner string) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	gzName := name + ".gz"
+	tmp := gzName + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	_ = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	if err := os.Rename(tmp, gzName); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	in.Close()
+	if err := changeOwnerOfFileFunc(gzName, owner); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
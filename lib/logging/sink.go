@@ -0,0 +1,56 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sinkWriter is a log destination other than a file or the console, such as
+// syslog or journald.
+type sinkWriter interface {
+	io.WriteCloser
+	RecordWriter
+}
+
+// IsSink returns true if the LogFile value names a log sink rather than a file:
+// "syslog" (local daemon), "syslog://host:port" (RFC 5424 over UDP, or
+// syslog+tcp:// and syslog+tls://) or "journald".
+func IsSink(file string) bool {
+	return file == "syslog" || file == "journald" ||
+		strings.HasPrefix(file, "syslog://") || strings.HasPrefix(file, "syslog+")
+}
+
+// sharedSink returns the sink for target, creating it on first use. Sinks are
+// shared by all loggers so reloads don't leak connections.
+func sharedSink(target, tag string) (sinkWriter, error) {
+	if tag == "" {
+		tag = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	}
+	key := target + "|" + tag
+	openRollingFilesLock.Lock()
+	defer openRollingFilesLock.Unlock()
+	if sink, ok := openSinks[key]; ok {
+		return sink, nil
+	}
+	var sink sinkWriter
+	var err error
+	if target == "journald" {
+		sink, err = newJournaldWriter(tag)
+	} else {
+		sink, err = newSyslogWriter(target, tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+	openSinks[key] = sink
+	return sink, nil
+}
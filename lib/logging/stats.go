@@ -0,0 +1,34 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import "sync"
+
+// Bytes written to each log file, across rotations and reloads
+var bytesWritten = struct {
+	sync.Mutex
+	m map[string]int64
+}{m: make(map[string]int64)}
+
+func addBytesWritten(file string, n int) {
+	bytesWritten.Lock()
+	bytesWritten.m[file] += int64(n)
+	bytesWritten.Unlock()
+}
+
+// BytesWritten returns the number of bytes written to each log file since
+// startup, by file name.
+func BytesWritten() map[string]int64 {
+	bytesWritten.Lock()
+	defer bytesWritten.Unlock()
+	m := make(map[string]int64, len(bytesWritten.m))
+	for file, n := range bytesWritten.m {
+		m[file] = n
+	}
+	return m
+}
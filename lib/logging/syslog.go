@@ -0,0 +1,193 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package logging
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	syslogFacilityDaemon = 3
+	sinkDialTimeout      = 5 * time.Second
+	sinkWriteTimeout     = 5 * time.Second
+	sinkRedialDelay      = 10 * time.Second
+	rfc5424TimeFormat    = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogWriter sends log records to a syslog server. Remote servers
+// (syslog://, syslog+tcp://, syslog+tls://) receive RFC 5424 messages, the
+// local syslog daemon receives traditional RFC 3164 style messages over its
+// Unix socket. The connection is made on first use and re-made after errors.
+type syslogWriter struct {
+	sync.Mutex
+	network  string // "udp", "tcp", "tls" or "" for the local daemon
+	address  string
+	tag      string
+	hostname string
+	conn     net.Conn
+	// Messages are dropped until then after a failed connect, so an
+	// unreachable server doesn't hold up every log write.
+	redialAfter time.Time
+}
+
+func newSyslogWriter(target, tag string) (*syslogWriter, error) {
+	sw := &syslogWriter{tag: tag}
+	sw.hostname, _ = os.Hostname()
+	if sw.hostname == "" {
+		sw.hostname = "-"
+	}
+	if target == "syslog" {
+		return sw, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "syslog", "syslog+udp":
+		sw.network = "udp"
+	case "syslog+tcp":
+		sw.network = "tcp"
+	case "syslog+tls":
+		sw.network = "tls"
+	default:
+		return nil, fmt.Errorf("unsupported syslog scheme %s", u.Scheme)
+	}
+	sw.address = u.Host
+	if u.Port() == "" {
+		port := "514"
+		if sw.network == "tls" {
+			port = "6514"
+		}
+		sw.address = net.JoinHostPort(u.Hostname(), port)
+	}
+	return sw, nil
+}
+
+func (sw *syslogWriter) Write(p []byte) (int, error) {
+	return writeLines(sw, p)
+}
+
+func (sw *syslogWriter) WriteRecord(r Record) error {
+	msg := sw.format(r)
+	sw.Lock()
+	defer sw.Unlock()
+	// Retry once on a fresh connection, e.g. if the server restarted
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if sw.conn == nil {
+			if time.Now().Before(sw.redialAfter) {
+				return errors.New("syslog server unavailable")
+			}
+			if sw.conn, err = sw.dial(); err != nil {
+				sw.redialAfter = time.Now().Add(sinkRedialDelay)
+				return err
+			}
+		}
+		_ = sw.conn.SetWriteDeadline(time.Now().Add(sinkWriteTimeout))
+		if _, err = sw.conn.Write(msg); err == nil {
+			return nil
+		}
+		sw.conn.Close()
+		sw.conn = nil
+	}
+	return err
+}
+
+func (sw *syslogWriter) dial() (net.Conn, error) {
+	switch sw.network {
+	case "tls":
+		dialer := &net.Dialer{Timeout: sinkDialTimeout}
+		return tls.DialWithDialer(dialer, "tcp", sw.address, &tls.Config{})
+	case "":
+		for _, socket := range localSyslogSockets {
+			for _, network := range []string{"unixgram", "unix"} {
+				if conn, err := net.DialTimeout(network, socket, sinkDialTimeout); err == nil {
+					return conn, nil
+				}
+			}
+		}
+		return nil, errors.New("unable to connect to the local syslog daemon")
+	default:
+		return net.DialTimeout(sw.network, sw.address, sinkDialTimeout)
+	}
+}
+
+func (sw *syslogWriter) format(r Record) []byte {
+	pri := syslogFacilityDaemon*8 + syslogSeverity(r.Level)
+	pid := r.PID
+	if pid == 0 {
+		pid = os.Getpid()
+	}
+	msg := r.Text()
+	if sw.network == "" {
+		// RFC 3164 style as expected by local daemons (and journald's /dev/log)
+		return []byte(fmt.Sprintf("<%d>%s %s[%d]: %s", pri, r.Time.Format(time.Stamp), sw.tag, pid, msg))
+	}
+	msgID := "-"
+	if r.Source != "" {
+		msgID = syslogHeaderField(r.Source, 32)
+	}
+	line := fmt.Sprintf("<%d>1 %s %s %s %d %s - %s", pri, r.Time.Format(rfc5424TimeFormat),
+		syslogHeaderField(sw.hostname, 255), syslogHeaderField(sw.tag, 48), pid, msgID, msg)
+	if sw.network == "udp" {
+		return []byte(line)
+	}
+	// Octet counting framing for stream transports (RFC 6587)
+	return []byte(fmt.Sprintf("%d %s", len(line), line))
+}
+
+// syslogHeaderField makes s valid as an RFC 5424 header field (printable
+// ASCII without spaces, limited length).
+func syslogHeaderField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func syslogSeverity(level string) int {
+	switch level {
+	case LevelError:
+		return 3
+	case LevelWarn:
+		return 4
+	default:
+		return 6
+	}
+}
+
+func (sw *syslogWriter) Close() error {
+	sw.Lock()
+	defer sw.Unlock()
+	if sw.conn == nil {
+		return nil
+	}
+	err := sw.conn.Close()
+	sw.conn = nil
+	return err
+}
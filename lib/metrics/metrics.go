@@ -0,0 +1,213 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+// Package metrics implements counters, gauges and summaries exposed in the
+// Prometheus text format.
+//
+// Silver only needs a handful of metrics, so as with logging we'll roll our own
+// rather than bring in the full Prometheus client and its dependencies.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	TypeCounter = "counter"
+	TypeGauge   = "gauge"
+	TypeSummary = "summary"
+)
+
+// Labels are the label names and values of a metric sample.
+type Labels map[string]string
+
+// Registry holds the current value of all metrics.
+type Registry struct {
+	sync.Mutex
+	families   map[string]*family
+	collectors []func(r *Registry)
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples map[string]*sample // By label key
+}
+
+type sample struct {
+	labels string // Formatted labels e.g. {service="x"}
+	value  float64
+	sum    float64 // Summaries only
+	count  uint64  // Summaries only
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Describe registers a metric's type and help text.
+func (r *Registry) Describe(name, typ, help string) {
+	r.Lock()
+	defer r.Unlock()
+	r.family(name).typ = typ
+	r.family(name).help = help
+}
+
+// Add increments a counter.
+func (r *Registry) Add(name string, labels Labels, v float64) {
+	r.Lock()
+	defer r.Unlock()
+	r.sample(name, labels).value += v
+}
+
+// Inc increments a counter by one.
+func (r *Registry) Inc(name string, labels Labels) {
+	r.Add(name, labels, 1)
+}
+
+// Set sets a gauge.
+func (r *Registry) Set(name string, labels Labels, v float64) {
+	r.Lock()
+	defer r.Unlock()
+	r.sample(name, labels).value = v
+}
+
+// Observe records a value (e.g. a duration in seconds) in a summary.
+func (r *Registry) Observe(name string, labels Labels, v float64) {
+	r.Lock()
+	defer r.Unlock()
+	s := r.sample(name, labels)
+	s.sum += v
+	s.count++
+}
+
+// AddCollector registers a function called to update metrics before they're
+// written, for values that are read rather than tracked.
+func (r *Registry) AddCollector(collect func(r *Registry)) {
+	r.Lock()
+	defer r.Unlock()
+	r.collectors = append(r.collectors, collect)
+}
+
+func (r *Registry) family(name string) *family {
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, typ: "untyped", samples: make(map[string]*sample)}
+		r.families[name] = f
+	}
+	return f
+}
+
+func (r *Registry) sample(name string, labels Labels) *sample {
+	f := r.family(name)
+	key := formatLabels(labels)
+	s, ok := f.samples[key]
+	if !ok {
+		s = &sample{labels: key}
+		f.samples[key] = s
+	}
+	return s
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.Lock()
+	collectors := r.collectors
+	r.Unlock()
+	for _, collect := range collectors {
+		collect(r)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		if len(f.samples) == 0 {
+			continue
+		}
+		if f.help != "" {
+			fmt.Fprintf(&b, "# HELP %s %s\n", name, escapeHelp(f.help))
+		}
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, f.typ)
+		keys := make([]string, 0, len(f.samples))
+		for key := range f.samples {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := f.samples[key]
+			if f.typ == TypeSummary {
+				fmt.Fprintf(&b, "%s_sum%s %s\n", name, s.labels, formatValue(s.sum))
+				fmt.Fprintf(&b, "%s_count%s %d\n", name, s.labels, s.count)
+				continue
+			}
+			fmt.Fprintf(&b, "%s%s %s\n", name, s.labels, formatValue(s.value))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves the metrics, e.g. on /metrics.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WriteText(w)
+}
+
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", name, escapeLabel(labels[name])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,61 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	r.Describe("test_runs_total", TypeCounter, "Test runs.")
+	r.Describe("test_duration_seconds", TypeSummary, "Test run time.")
+	r.Describe("test_up", TypeGauge, "Whether it's up.")
+	r.Inc("test_runs_total", Labels{"task": "a"})
+	r.Inc("test_runs_total", Labels{"task": "a"})
+	r.Inc("test_runs_total", Labels{"task": `b"\`})
+	r.Observe("test_duration_seconds", Labels{"task": "a"}, 1.5)
+	r.Observe("test_duration_seconds", Labels{"task": "a"}, 0.5)
+	r.AddCollector(func(r *Registry) {
+		r.Set("test_up", nil, 1)
+	})
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP test_duration_seconds Test run time.
+# TYPE test_duration_seconds summary
+test_duration_seconds_sum{task="a"} 2
+test_duration_seconds_count{task="a"} 2
+# HELP test_runs_total Test runs.
+# TYPE test_runs_total counter
+test_runs_total{task="a"} 2
+test_runs_total{task="b\"\\"} 1
+# HELP test_up Whether it's up.
+# TYPE test_up gauge
+test_up 1
+`
+	if b.String() != expected {
+		t.Errorf("Unexpected output:\n%s", b.String())
+	}
+}
+
+func TestWriteText_SkipsMetricsWithoutSamples(t *testing.T) {
+	r := NewRegistry()
+	r.Describe("test_unused", TypeGauge, "Never set.")
+
+	var b strings.Builder
+	_ = r.WriteText(&b)
+
+	if b.Len() != 0 {
+		t.Errorf("Expected no output, got:\n%s", b.String())
+	}
+}
@@ -0,0 +1,32 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package osutils_test
+
+import (
+	"testing"
+
+	"github.com/papercutsoftware/silver/lib/osutils"
+)
+
+func TestIsLocalhost(t *testing.T) {
+	for host, expected := range map[string]bool{
+		"localhost":   true,
+		"LocalHost":   true,
+		"127.0.0.1":   true,
+		"127.1.2.3":   true,
+		"::1":         true,
+		"":            false,
+		"0.0.0.0":     false,
+		"example.com": false,
+		"192.168.1.1": false,
+	} {
+		if got := osutils.IsLocalhost(host); got != expected {
+			t.Errorf("IsLocalhost(%q) = %v, expected %v", host, got, expected)
+		}
+	}
+}
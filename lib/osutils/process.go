@@ -70,3 +70,10 @@ func ProcessKillHard(pid int) error {
 func ProcessSignalQuit(pid int) error {
 	return processSignalQuit(pid)
 }
+
+// ProcessSignalDump asks a process to dump its threads without stopping
+// (SIGQUIT on Unix and Control-Break on Windows). Java prints a thread dump to
+// STDOUT.
+func ProcessSignalDump(pid int) error {
+	return processSignalDump(pid)
+}
@@ -41,6 +41,10 @@ func processSignalQuit(pid int) error {
 	return nil
 }
 
+func processSignalDump(pid int) error {
+	return sendSignal(pid, syscall.SIGQUIT)
+}
+
 func sendSignal(pid int, sig os.Signal) error {
 	process, err := os.FindProcess(pid)
 	if err != nil {
@@ -55,6 +55,10 @@ func processSignalQuit(pid int) error {
 	return nil
 }
 
+func processSignalDump(pid int) error {
+	return sendCtrlBreak(pid)
+}
+
 func processSysProcAttrForQuit() *syscall.SysProcAttr {
 	return &syscall.SysProcAttr{
 		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP,
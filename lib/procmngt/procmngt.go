@@ -9,6 +9,7 @@ package procmngt
 
 import (
 	"io"
+	"os"
 	"os/exec"
 	"sync"
 	"syscall"
@@ -35,17 +36,24 @@ type ExecConfig struct {
 	Stderr           io.Writer
 	Stdin            io.Reader
 	Env              []string
+	OnStart          func(pid int)                // Called once the process has started
+	OnExit           func(state *os.ProcessState) // Called once the process has exited
 }
 
 type executable struct {
 	cmd              *exec.Cmd
 	gracefulShutdown time.Duration
+	onStart          func(pid int)
+	onExit           func(state *os.ProcessState)
 }
 
 func (c executable) Execute(terminate <-chan struct{}) (exitCode int, err error) {
 	if err := c.cmd.Start(); err != nil {
 		return errorExitCode, err
 	}
+	if c.onStart != nil {
+		c.onStart(c.cmd.Process.Pid)
+	}
 	var done sync.WaitGroup
 	done.Add(1)
 	complete := make(chan struct{})
@@ -68,6 +76,9 @@ func (c executable) Execute(terminate <-chan struct{}) (exitCode int, err error)
 			}
 		}
 	}
+	if c.onExit != nil {
+		c.onExit(c.cmd.ProcessState)
+	}
 	//Have to call these here to avoid race condition
 	close(complete)
 	done.Wait()
@@ -110,6 +121,8 @@ func NewExecutable(execConf ExecConfig) Executable {
 	e = executable{
 		cmd:              setupCmd(execConf),
 		gracefulShutdown: execConf.GracefulShutDown,
+		onStart:          execConf.OnStart,
+		onExit:           execConf.OnExit,
 	}
 	if isStartupDelayedCmd(execConf) {
 		e = startupDelayedExecutable{
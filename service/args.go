@@ -26,6 +26,8 @@ var validArgs = []string{
 	"uninstall",
 	"start",
 	"stop",
+	"status",
+	"logs",
 	"validate",
 	"run",
 	"command",
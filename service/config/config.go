@@ -14,9 +14,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/url"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/papercutsoftware/silver/lib/logging"
 	"github.com/papercutsoftware/silver/lib/osutils"
+	"github.com/robfig/cron"
 )
 
 const stopFileName = ".stop"
@@ -31,6 +37,14 @@ type Config struct {
 	StartupTasks       []StartupTask
 	ScheduledTasks     []ScheduledTask
 	Commands           []Command
+	LogRedact          LogRedact
+	EventHandlers      []EventHandler
+}
+
+// LogRedact is the patterns of sensitive text to remove from all log output.
+type LogRedact struct {
+	Patterns    []string
+	Replacement string // Defaults to [REDACTED]
 }
 
 type ServiceDescription struct {
@@ -40,15 +54,35 @@ type ServiceDescription struct {
 }
 
 type ServiceConfig struct {
+	LogFileConfig
 	StopFile               string
 	ReloadFile             string
-	LogFile                string
-	LogFileMaxSizeMb       int64
-	LogFileMaxBackupFiles  int
 	PidFile                string
+	StatusFile             string
 	UserLevel              bool
 	UserName               string
 	LogFileTimestampFormat string
+	LogFormat              string
+	LogLevel               string // debug, info (default), warn or error
+	ReloadOnConfigChange   bool
+	ReloadDebounceSecs     int
+	CrashBundleDir         string // Crash bundles are only written when this is set
+	CrashBundleMaxCount    int
+	CrashBundleMaxAgeDays  int
+	CrashBundleMaxFileMb   int64  // CrashFiles larger than this are listed but not copied. Default 100, -1 for no limit
+	MetricsAddress         string // e.g. "127.0.0.1:9464" to serve Prometheus metrics on /metrics. Empty to disable
+}
+
+// LogFileConfig is the log file and rotation settings, used for Silver's main
+// log and optionally for a service or task's own output.
+type LogFileConfig struct {
+	LogFile               string
+	LogFileMaxSizeMb      int64
+	LogFileMaxBackupFiles int
+	LogFileRotation       string // "daily" or "hourly" in addition to size based rotation
+	LogFileCompress       bool
+	LogFileMaxAgeDays     int
+	LogFileMaxTotalSizeMb int64
 }
 
 type command struct {
@@ -58,11 +92,22 @@ type command struct {
 
 type Service struct {
 	command
+	LogFileConfig
 	GracefulShutdownTimeoutSecs int
 	MaxCrashCountPerHour        int
 	RestartDelaySecs            int
 	StartupDelaySecs            int
+	StopFile                    string
 	MonitorPing                 *MonitorPing
+	Monitors                    []MonitorPing // Checked along with MonitorPing
+	MonitorPolicy               string        // Restart when "any" (default) or "all" monitors fail
+	MonitorFlapCount            int           // Monitor restarts within MonitorFlapWindowMins treated as flapping. 0 to disable
+	MonitorFlapWindowMins       int           // Default 60
+	MonitorFlapAction           string        // "alert" (default) or "stop"
+	HangDiagnostics             *HangDiagnostics
+	OutputContinuationPattern   string   // Output lines matching are merged into the previous line (e.g. stack traces)
+	OutputBufferLines           int      // Recent output kept in memory for the logs command
+	CrashFiles                  []string // Globs of files (e.g. core dumps) to add to crash bundles
 }
 
 type MonitorPing struct {
@@ -71,23 +116,176 @@ type MonitorPing struct {
 	TimeoutSecs           int
 	StartupDelaySecs      int
 	RestartOnFailureCount int
+	Args                  []string // Arguments for an exec:// health check program
+
+	// http(s) monitor options
+	Method             string            // Default GET
+	Headers            map[string]string // Values may reference environment variables e.g. "Bearer ${TOKEN}"
+	ExpectedStatus     []string          // e.g. ["200", "204"] or ["200-299"]. Default 200
+	BodyPattern        string            // Regular expression the response body must match
+	JSONPath           string            // e.g. "status" or "checks[0].status", must exist in the JSON response
+	JSONValue          string            // Value expected at JSONPath
+	Redirects          string            // "follow" (default) or "none"
+	CACertFile         string
+	ClientCertFile     string
+	ClientKeyFile      string
+	InsecureSkipVerify bool // localhost only. Also applies to tls:// and grpcs://
+	UnixSocket         string
+
+	// unix, udp, tls and grpc monitor options
+	Echo                  bool   // unix and udp: expect a unique ping to be echoed back
+	Send                  string // unix and udp: request to send. udp default "ping"
+	Expect                string // unix and udp: text expected in the response
+	CertExpiryWarningDays int    // tls and grpcs: warn when the certificate expires sooner. Default 14, -1 to disable
+
+	// file monitor options
+	MaxAgeSecs int // Fail if the file wasn't modified within this, rather than checking it changed since the last ping
+}
+
+// HangDiagnostics are gathered before the monitors restart an unresponsive
+// service. ${PID} in the Path or Args is replaced with the service's PID.
+type HangDiagnostics struct {
+	Action      string   // "signal" (SIGQUIT or Control-Break e.g. a Java thread dump), "command" or "copy"
+	Path        string   // Command to run (e.g. jstack), or file to copy
+	Args        []string // Command arguments e.g. ["${PID}"]
+	Dir         string   // Where output is saved. Default "diagnostics"
+	TimeoutSecs int      // Command timeout, or how long the service's output is saved after the signal. Default 10
+}
+
+func (h HangDiagnostics) validate() error {
+	switch h.Action {
+	case "signal":
+	case "command", "copy":
+		if h.Path == "" {
+			return fmt.Errorf("Services.HangDiagnostics.Path is required for the \"%s\" action", h.Action)
+		}
+	default:
+		return fmt.Errorf("Services.HangDiagnostics.Action must be \"signal\", \"command\" or \"copy\", got \"%s\"", h.Action)
+	}
+	return nil
+}
+
+// MonitorSchemes are the supported MonitorPing URL schemes
+var MonitorSchemes = []string{"tcp", "echo", "http", "https", "file", "exec", "unix", "udp", "tls", "grpc", "grpcs"}
+
+var statusRange = regexp.MustCompile(`^[1-5][0-9][0-9](-[1-5][0-9][0-9])?$`)
+
+// validate names the monitor by its field in errors e.g. "Services[0].Monitors[1]"
+func (m MonitorPing) validate(field string) error {
+	if m.URL != "" {
+		scheme, _, _ := strings.Cut(m.URL, "://")
+		if !Contains(MonitorSchemes, strings.ToLower(scheme)) {
+			return fmt.Errorf("%s.URL scheme must be one of %s, got \"%s\"", field, strings.Join(MonitorSchemes, ", "), m.URL)
+		}
+	}
+	for _, status := range m.ExpectedStatus {
+		if !statusRange.MatchString(status) {
+			return fmt.Errorf("%s.ExpectedStatus must be status codes (e.g. \"204\") or ranges (e.g. \"200-299\"), got \"%s\"", field, status)
+		}
+	}
+	if _, err := regexp.Compile(m.BodyPattern); err != nil {
+		return fmt.Errorf("%s.BodyPattern is invalid: %v", field, err)
+	}
+	switch m.Redirects {
+	case "", "follow", "none":
+	default:
+		return fmt.Errorf("%s.Redirects must be \"follow\" or \"none\", got \"%s\"", field, m.Redirects)
+	}
+	if m.ClientCertFile != "" && m.ClientKeyFile == "" {
+		return fmt.Errorf("%s.ClientKeyFile is required with ClientCertFile", field)
+	}
+	if m.InsecureSkipVerify {
+		u, err := url.Parse(m.URL)
+		if err != nil || !osutils.IsLocalhost(u.Hostname()) {
+			return fmt.Errorf("%s.InsecureSkipVerify is only allowed for localhost, got \"%s\"", field, m.URL)
+		}
+	}
+	return nil
+}
+
+func (m *MonitorPing) applyDefaults() {
+	if m.CertExpiryWarningDays == 0 {
+		m.CertExpiryWarningDays = 14
+	}
+	// A health check program that hangs must not block the monitor
+	if m.TimeoutSecs == 0 && strings.HasPrefix(strings.ToLower(m.URL), "exec://") {
+		m.TimeoutSecs = 10
+	}
 }
 
 type Task struct {
 	command
-	TimeoutSecs            int
-	StartupDelaySecs       int
-	StartupRandomDelaySecs int
+	LogFileConfig
+	TimeoutSecs               int
+	StartupDelaySecs          int
+	StartupRandomDelaySecs    int
+	OutputContinuationPattern string
+	Retries                   int   // Times a failed task is run again
+	RetryDelaySecs            int   // Delay before the first retry, doubling each time
+	RetryMaxDelaySecs         int   // Caps the retry delay. 0 for no limit
+	SuccessExitCodes          []int // Exit codes that mean success. Default [0]
 }
 
 type StartupTask struct {
 	Task
-	Async bool
+	Async       bool
+	OnFailure   string // Synchronous tasks only: "continue" (default), "abort" to not start the services, or "task" to run FailureTask
+	FailureTask *Task  // Run when OnFailure is "task". Services are started only if it succeeds
+}
+
+func (t StartupTask) validate(section string) error {
+	if err := t.Task.validate(section); err != nil {
+		return err
+	}
+	switch t.OnFailure {
+	case "", "continue":
+		return nil
+	case "abort", "task":
+	default:
+		return fmt.Errorf("%s.OnFailure must be \"continue\", \"abort\" or \"task\", got \"%s\"", section, t.OnFailure)
+	}
+	if t.Async {
+		return fmt.Errorf("%s.OnFailure \"%s\" needs a synchronous task, not Async", section, t.OnFailure)
+	}
+	if t.OnFailure == "task" {
+		if t.FailureTask == nil || t.FailureTask.Path == "" {
+			return fmt.Errorf("%s.OnFailure \"task\" needs a FailureTask with a Path", section)
+		}
+		return t.FailureTask.validate(section + ".FailureTask")
+	}
+	return nil
 }
 
 type ScheduledTask struct {
 	Task
-	Schedule string
+	Schedule          string // 6-field cron (with seconds) or a descriptor e.g. "@hourly", "@every 15m"
+	Timezone          string // IANA time zone e.g. "America/New_York" the Schedule is in. Default local time
+	CatchUp           bool   // Run once at startup if a run was missed while the service was stopped
+	ConcurrencyPolicy string // When the previous run is still going: "allow" (default), "skip", "queue" or "replace"
+}
+
+func (t ScheduledTask) validate(section string) error {
+	if err := t.Task.validate(section); err != nil {
+		return err
+	}
+	if _, err := cron.Parse(t.Schedule); err != nil {
+		return fmt.Errorf("%s.Schedule \"%s\" is invalid: %v", section, t.Schedule, err)
+	}
+	if every := strings.TrimPrefix(t.Schedule, "@every "); every != t.Schedule {
+		// cron silently rounds these up to a second
+		if d, _ := time.ParseDuration(every); d < time.Second {
+			return fmt.Errorf("%s.Schedule \"%s\" must be at least 1s", section, t.Schedule)
+		}
+	}
+	if _, err := time.LoadLocation(t.Timezone); err != nil {
+		return fmt.Errorf("%s.Timezone \"%s\" is invalid: %v", section, t.Timezone, err)
+	}
+	switch t.ConcurrencyPolicy {
+	case "", "allow", "skip", "queue", "replace":
+	default:
+		return fmt.Errorf("%s.ConcurrencyPolicy must be \"allow\", \"skip\", \"queue\" or \"replace\", got \"%s\"", section, t.ConcurrencyPolicy)
+	}
+	return nil
 }
 
 type Command struct {
@@ -96,6 +294,34 @@ type Command struct {
 	TimeoutSecs int
 }
 
+// EventHandler is notified of lifecycle events by running a command with
+// the event JSON on STDIN, or by POSTing the event JSON to a webhook.
+type EventHandler struct {
+	command
+	Events         []string // Event types handled, see EventTypes
+	WebhookURL     string
+	Headers        map[string]string // Extra webhook request headers, e.g. Authorization
+	TimeoutSecs    int               // Command run or webhook request time limit. Default 30
+	Retries        int               // Webhook retries after a failure. Default 3, -1 for none
+	RetryDelaySecs int               // Delay before the first retry, doubling each time. Default 5, -1 to retry immediately
+}
+
+// EventTypes are the lifecycle events handlers can subscribe to.
+var EventTypes = []string{
+	"service-started",
+	"service-stopped",
+	"service-crashed",
+	"service-restarted",
+	"crash-limit-exceeded",
+	"monitor-failed",
+	"monitor-flapping",
+	"task-failed",
+	"task-skipped",
+	"startup-aborted",
+	"update-applied",
+	"reload",
+}
+
 type ReplacementVars struct {
 	ServiceName string
 	ServiceRoot string
@@ -110,14 +336,15 @@ func LoadConfig(path string, vars ReplacementVars) (conf *Config, err error) {
 	if err != nil {
 		return nil, err
 	}
-	err = conf.validate()
+	err = conf.Validate()
 	if err != nil {
 		return nil, err
 	}
 	return conf, nil
 }
 
-// MergeInclude merges in an include file.  Include files can contain services, tasks and commands
+// MergeInclude merges in an include file.  Include files can contain services, tasks, commands
+// and log redaction patterns
 func MergeInclude(conf Config, path string, vars ReplacementVars) (*Config, error) {
 	include, err := load(path, vars)
 	if err != nil {
@@ -128,6 +355,8 @@ func MergeInclude(conf Config, path string, vars ReplacementVars) (*Config, erro
 	conf.StartupTasks = append(conf.StartupTasks, include.StartupTasks...)
 	conf.ScheduledTasks = append(conf.ScheduledTasks, include.ScheduledTasks...)
 	conf.Commands = append(conf.Commands, include.Commands...)
+	conf.LogRedact.Patterns = append(conf.LogRedact.Patterns, include.LogRedact.Patterns...)
+	conf.EventHandlers = append(conf.EventHandlers, include.EventHandlers...)
 	for k, v := range include.EnvironmentVars {
 		conf.EnvironmentVars[k] = v
 	}
@@ -186,10 +415,141 @@ func (conf *Config) FindCommand(cmdName string) *Command {
 	return nil
 }
 
-func (conf *Config) validate() error {
+// Validate checks the config is complete and consistent.
+func (conf *Config) Validate() error {
 	if conf.ServiceDescription.DisplayName == "" {
 		return fmt.Errorf("ServiceDescription.DisplayName is required configuration")
 	}
+	switch conf.ServiceConfig.LogFormat {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("ServiceConfig.LogFormat must be \"text\" or \"json\", got \"%s\"", conf.ServiceConfig.LogFormat)
+	}
+	if !logging.ValidLevel(conf.ServiceConfig.LogLevel) {
+		return fmt.Errorf("ServiceConfig.LogLevel must be \"debug\", \"info\", \"warn\" or \"error\", got \"%s\"", conf.ServiceConfig.LogLevel)
+	}
+	if _, err := logging.NewRedactor(conf.LogRedact.Patterns, conf.LogRedact.Replacement); err != nil {
+		return fmt.Errorf("LogRedact: %v", err)
+	}
+	if addr := conf.ServiceConfig.MetricsAddress; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("ServiceConfig.MetricsAddress must be host:port, got \"%s\"", addr)
+		}
+	}
+	if err := conf.ServiceConfig.LogFileConfig.validate("ServiceConfig"); err != nil {
+		return err
+	}
+	for i, s := range conf.Services {
+		if err := s.LogFileConfig.validate("Services"); err != nil {
+			return err
+		}
+		if err := validatePattern("Services", s.OutputContinuationPattern); err != nil {
+			return err
+		}
+		if s.MonitorPing != nil {
+			if err := s.MonitorPing.validate(fmt.Sprintf("Services[%d].MonitorPing", i)); err != nil {
+				return err
+			}
+		}
+		for j, m := range s.Monitors {
+			if err := m.validate(fmt.Sprintf("Services[%d].Monitors[%d]", i, j)); err != nil {
+				return err
+			}
+		}
+		switch s.MonitorPolicy {
+		case "", "any", "all":
+		default:
+			return fmt.Errorf("Services.MonitorPolicy must be \"any\" or \"all\", got \"%s\"", s.MonitorPolicy)
+		}
+		if s.HangDiagnostics != nil {
+			if err := s.HangDiagnostics.validate(); err != nil {
+				return err
+			}
+		}
+		switch s.MonitorFlapAction {
+		case "", "alert", "stop":
+		default:
+			return fmt.Errorf("Services.MonitorFlapAction must be \"alert\" or \"stop\", got \"%s\"", s.MonitorFlapAction)
+		}
+	}
+	for _, h := range conf.EventHandlers {
+		if err := h.validate(); err != nil {
+			return err
+		}
+	}
+	for _, t := range conf.StartupTasks {
+		if err := t.validate("StartupTasks"); err != nil {
+			return err
+		}
+	}
+	for _, t := range conf.ScheduledTasks {
+		if err := t.validate("ScheduledTasks"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t Task) validate(section string) error {
+	if err := t.LogFileConfig.validate(section); err != nil {
+		return err
+	}
+	if t.Retries < 0 || t.RetryDelaySecs < 0 || t.RetryMaxDelaySecs < 0 {
+		return fmt.Errorf("%s.Retries, RetryDelaySecs and RetryMaxDelaySecs can't be negative", section)
+	}
+	return validatePattern(section, t.OutputContinuationPattern)
+}
+
+func (h EventHandler) validate() error {
+	if (h.Path == "") == (h.WebhookURL == "") {
+		return fmt.Errorf("EventHandlers must have either a Path or a WebhookURL")
+	}
+	if h.WebhookURL != "" {
+		u, err := url.Parse(h.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("EventHandlers.WebhookURL must be an http or https URL, got \"%s\"", h.WebhookURL)
+		}
+	}
+	if h.Retries < -1 || h.RetryDelaySecs < -1 {
+		return fmt.Errorf("EventHandlers.Retries and RetryDelaySecs must be -1 (none) or more")
+	}
+	if len(h.Events) == 0 {
+		return fmt.Errorf("EventHandlers.Events is required")
+	}
+	for _, e := range h.Events {
+		if !Contains(EventTypes, e) {
+			return fmt.Errorf("EventHandlers.Events has unknown event \"%s\", must be one of %s", e, strings.Join(EventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// Contains reports whether v is one of values.
+func Contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func validatePattern(section, pattern string) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("%s.OutputContinuationPattern is invalid: %v", section, err)
+	}
+	return nil
+}
+
+func (lf LogFileConfig) validate(section string) error {
+	switch lf.LogFileRotation {
+	case "", "daily", "hourly":
+	default:
+		return fmt.Errorf("%s.LogFileRotation must be \"daily\" or \"hourly\", got \"%s\"", section, lf.LogFileRotation)
+	}
+	if lf.LogFileMaxAgeDays < 0 || lf.LogFileMaxTotalSizeMb < 0 {
+		return fmt.Errorf("%s.LogFileMaxAgeDays and LogFileMaxTotalSizeMb can't be negative", section)
+	}
 	return nil
 }
 
@@ -201,22 +561,71 @@ func (conf *Config) applyDefaults() {
 		conf.ServiceConfig.ReloadFile = ReloadFileName
 	}
 
+	if conf.ServiceConfig.ReloadDebounceSecs == 0 {
+		conf.ServiceConfig.ReloadDebounceSecs = 5
+	}
+
+	if conf.ServiceConfig.CrashBundleMaxCount == 0 {
+		conf.ServiceConfig.CrashBundleMaxCount = 20
+	}
+	if conf.ServiceConfig.CrashBundleMaxAgeDays == 0 {
+		conf.ServiceConfig.CrashBundleMaxAgeDays = 30
+	}
+	if conf.ServiceConfig.CrashBundleMaxFileMb == 0 {
+		conf.ServiceConfig.CrashBundleMaxFileMb = 100
+	}
+
 	if conf.ServiceConfig.LogFileMaxSizeMb == 0 {
 		conf.ServiceConfig.LogFileMaxSizeMb = 50
 	}
-	if conf.ServiceConfig.LogFileMaxBackupFiles == 0 {
-		conf.ServiceConfig.LogFileMaxBackupFiles = 1
+	// Age or total size retention replaces the default backup file count
+	lf := &conf.ServiceConfig.LogFileConfig
+	if lf.LogFileMaxBackupFiles == 0 && lf.LogFileMaxAgeDays == 0 && lf.LogFileMaxTotalSizeMb == 0 {
+		lf.LogFileMaxBackupFiles = 1
 	}
 
 	if conf.EnvironmentVars == nil {
 		conf.EnvironmentVars = make(map[string]string)
 	}
 
+	for i := range conf.EventHandlers {
+		h := &conf.EventHandlers[i]
+		if h.TimeoutSecs == 0 {
+			h.TimeoutSecs = 30
+		}
+		if h.Retries == 0 {
+			h.Retries = 3
+		}
+		if h.RetryDelaySecs == 0 {
+			h.RetryDelaySecs = 5
+		}
+	}
+
 	// Default graceful is 5 seconds
 	for i := range conf.Services {
+		if m := conf.Services[i].MonitorPing; m != nil {
+			m.applyDefaults()
+		}
+		for j := range conf.Services[i].Monitors {
+			conf.Services[i].Monitors[j].applyDefaults()
+		}
+		if conf.Services[i].OutputBufferLines == 0 {
+			conf.Services[i].OutputBufferLines = 1000
+		}
 		if conf.Services[i].GracefulShutdownTimeoutSecs == 0 {
 			conf.Services[i].GracefulShutdownTimeoutSecs = 5
 		}
+		if conf.Services[i].MonitorFlapWindowMins == 0 {
+			conf.Services[i].MonitorFlapWindowMins = 60
+		}
+		if h := conf.Services[i].HangDiagnostics; h != nil {
+			if h.Dir == "" {
+				h.Dir = "diagnostics"
+			}
+			if h.TimeoutSecs == 0 {
+				h.TimeoutSecs = 10
+			}
+		}
 	}
 }
 
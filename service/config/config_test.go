@@ -204,6 +204,10 @@ func TestLocalConfig_Defaults_OK(t *testing.T) {
 		t.Error("Expected default StopFile=.stop")
 	}
 
+	if c.ServiceConfig.CrashBundleDir != "" {
+		t.Errorf("Expected crash bundles to be off by default, got CrashBundleDir=%s", c.ServiceConfig.CrashBundleDir)
+	}
+
 	for _, service := range c.Services {
 		if service.GracefulShutdownTimeoutSecs != 5 {
 			t.Error("Expected default GracefulShutdownTimeoutSecs=5")
@@ -250,6 +254,401 @@ func TestLoadConfig_MinimalConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_ServiceLogFile(t *testing.T) {
+	// Arrange
+	testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "ServiceConfig" : {
+            "LogFile" : "${ServiceName}.log",
+            "LogFileMaxSizeMb" : 20
+        },
+        "Services" : [
+            {
+                "Path" : "test/path/1",
+                "LogFile" : "${ServiceRoot}/logs/service1.log",
+                "LogFileMaxSizeMb" : 100,
+                "LogFileMaxBackupFiles" : 10
+            }
+        ],
+        "ScheduledTasks" : [
+            {
+                "Schedule" : "0 30 * * * *",
+                "Path" : "mytask",
+                "LogFile" : "mytask.log"
+            }
+        ]
+    }`
+	tmpFile := writeTestConfig(t, testConfig)
+	defer os.Remove(tmpFile)
+
+	vars := config.ReplacementVars{
+		ServiceName: "MyServiceName",
+		ServiceRoot: "/opt/myservice",
+	}
+
+	// Act
+	c, err := config.LoadConfig(tmpFile, vars)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	if c.ServiceConfig.LogFile != "MyServiceName.log" || c.ServiceConfig.LogFileMaxSizeMb != 20 {
+		t.Errorf("Main log settings not parsed correctly: %+v", c.ServiceConfig.LogFileConfig)
+	}
+	svc := c.Services[0]
+	if svc.LogFile != "/opt/myservice/logs/service1.log" || svc.LogFileMaxSizeMb != 100 || svc.LogFileMaxBackupFiles != 10 {
+		t.Errorf("Service log settings not parsed correctly: %+v", svc.LogFileConfig)
+	}
+	if c.ScheduledTasks[0].LogFile != "mytask.log" {
+		t.Errorf("Task log file not parsed correctly: %+v", c.ScheduledTasks[0].LogFileConfig)
+	}
+}
+
+func TestLoadConfig_LogRetention(t *testing.T) {
+	// Arrange
+	testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "ServiceConfig" : {
+            "LogFileRotation" : "daily",
+            "LogFileCompress" : true,
+            "LogFileMaxAgeDays" : 30
+        }
+    }`
+	tmpFile := writeTestConfig(t, testConfig)
+	defer os.Remove(tmpFile)
+
+	// Act
+	c, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	lf := c.ServiceConfig.LogFileConfig
+	if lf.LogFileRotation != "daily" || !lf.LogFileCompress || lf.LogFileMaxAgeDays != 30 {
+		t.Errorf("Log retention settings not parsed correctly: %+v", lf)
+	}
+	if lf.LogFileMaxBackupFiles != 0 {
+		t.Errorf("Expected no backup file count limit with age retention, got %d", lf.LogFileMaxBackupFiles)
+	}
+}
+
+func TestLoadConfig_InvalidLogRotation_ShouldError(t *testing.T) {
+	// Arrange
+	testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "ServiceConfig" : {
+            "LogFileRotation" : "weekly"
+        }
+    }`
+	tmpFile := writeTestConfig(t, testConfig)
+	defer os.Remove(tmpFile)
+
+	// Act
+	_, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "LogFileRotation") {
+		t.Errorf("Expected LogFileRotation error, got: %v", err)
+	}
+}
+
+func TestLoadConfig_InvalidMetricsAddress_ShouldError(t *testing.T) {
+	// Arrange
+	testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "ServiceConfig" : {
+            "MetricsAddress" : "9464"
+        }
+    }`
+	tmpFile := writeTestConfig(t, testConfig)
+	defer os.Remove(tmpFile)
+
+	// Act
+	_, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "MetricsAddress") {
+		t.Errorf("Expected MetricsAddress error, got: %v", err)
+	}
+}
+
+func TestLoadConfig_EventHandlers(t *testing.T) {
+	// Arrange
+	testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "EventHandlers" : [
+            {
+                "Events" : ["crash-limit-exceeded", "monitor-failed"],
+                "WebhookURL" : "https://alerts.example.com/hook"
+            },
+            {
+                "Events" : ["task-failed"],
+                "Path" : "notify.sh",
+                "Retries" : -1
+            }
+        ]
+    }`
+	tmpFile := writeTestConfig(t, testConfig)
+	defer os.Remove(tmpFile)
+
+	// Act
+	c, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	h := c.EventHandlers[0]
+	if h.TimeoutSecs != 30 || h.Retries != 3 || h.RetryDelaySecs != 5 {
+		t.Errorf("Expected default timeout and retries, got %+v", h)
+	}
+	if c.EventHandlers[1].Path != "notify.sh" {
+		t.Error("Problem extracting handler path")
+	}
+	if c.EventHandlers[1].Retries != -1 {
+		t.Errorf("Expected no retries to be kept, got %d", c.EventHandlers[1].Retries)
+	}
+}
+
+func TestLoadConfig_InvalidEventHandler_ShouldError(t *testing.T) {
+	// Arrange
+	testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "EventHandlers" : [
+            {
+                "Events" : ["service-exploded"],
+                "Path" : "notify.sh"
+            }
+        ]
+    }`
+	tmpFile := writeTestConfig(t, testConfig)
+	defer os.Remove(tmpFile)
+
+	// Act
+	_, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "service-exploded") {
+		t.Errorf("Expected unknown event error, got: %v", err)
+	}
+}
+
+func TestLoadConfig_ExecMonitorDefaultTimeout(t *testing.T) {
+	// Arrange
+	testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "Services" : [
+            {
+                "Path" : "test/path/1",
+                "MonitorPing" : { "URL": "exec://bin/my-app", "Args": ["--healthcheck"] },
+                "Monitors" : [
+                    { "URL": "exec://bin/my-db-check" },
+                    { "URL": "exec://bin/my-slow-check", "TimeoutSecs": 60 },
+                    { "URL": "tcp://localhost:8080" }
+                ]
+            }
+        ]
+    }`
+	tmpFile := writeTestConfig(t, testConfig)
+	defer os.Remove(tmpFile)
+
+	// Act
+	c, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	svc := c.Services[0]
+	if svc.MonitorPing.TimeoutSecs != 10 || svc.Monitors[0].TimeoutSecs != 10 {
+		t.Errorf("Expected exec health checks to default to a 10 second timeout, got %d and %d",
+			svc.MonitorPing.TimeoutSecs, svc.Monitors[0].TimeoutSecs)
+	}
+	if svc.Monitors[1].TimeoutSecs != 60 {
+		t.Errorf("Expected the configured timeout to be kept, got %d", svc.Monitors[1].TimeoutSecs)
+	}
+	if svc.Monitors[2].TimeoutSecs != 0 {
+		t.Errorf("Expected no default timeout for other monitors, got %d", svc.Monitors[2].TimeoutSecs)
+	}
+}
+
+func TestLoadConfig_InvalidMonitorHTTPOptions_ShouldError(t *testing.T) {
+	for _, monitor := range []string{
+		`{"URL": "http://localhost/health", "ExpectedStatus": ["2xx"]}`,
+		`{"URL": "https://example.com/health", "InsecureSkipVerify": true}`,
+		`{"URL": "http://localhost/health", "Redirects": "sometimes"}`,
+		`{"URL": "ftp://localhost:21"}`,
+		`{"URL": "localhost:8080"}`,
+	} {
+		// Arrange
+		testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "Services" : [
+            {
+                "Path" : "test/path/1",
+                "Monitors" : [` + monitor + `]
+            }
+        ]
+    }`
+		tmpFile := writeTestConfig(t, testConfig)
+		defer os.Remove(tmpFile)
+
+		// Act
+		_, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+		// Assert
+		if err == nil || !strings.Contains(err.Error(), "Services[0].Monitors[0].") {
+			t.Errorf("Expected Services[0].Monitors[0] error for %s, got: %v", monitor, err)
+		}
+	}
+}
+
+func TestLoadConfig_InvalidMonitor_ErrorNamesTheMonitor(t *testing.T) {
+	// Arrange
+	testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "Services" : [
+            {
+                "Path" : "test/path/1"
+            },
+            {
+                "Path" : "test/path/2",
+                "MonitorPing" : { "URL": "http://localhost/health" },
+                "Monitors" : [
+                    { "URL": "tcp://localhost:8080" },
+                    { "URL": "http://localhost/health", "Redirects": "sometimes" }
+                ]
+            }
+        ]
+    }`
+	tmpFile := writeTestConfig(t, testConfig)
+	defer os.Remove(tmpFile)
+
+	// Act
+	_, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+	// Assert
+	if err == nil || !strings.HasPrefix(err.Error(), "Services[1].Monitors[1].Redirects") {
+		t.Errorf("Expected the error to name Services[1].Monitors[1], got: %v", err)
+	}
+}
+
+func TestLoadConfig_InvalidHangDiagnostics_ShouldError(t *testing.T) {
+	for _, diagnostics := range []string{
+		`{"Action": "dump"}`,
+		`{"Action": "command"}`,
+	} {
+		// Arrange
+		testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "Services" : [
+            {
+                "Path" : "test/path/1",
+                "HangDiagnostics" : ` + diagnostics + `
+            }
+        ]
+    }`
+		tmpFile := writeTestConfig(t, testConfig)
+		defer os.Remove(tmpFile)
+
+		// Act
+		_, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+		// Assert
+		if err == nil || !strings.Contains(err.Error(), "HangDiagnostics") {
+			t.Errorf("Expected HangDiagnostics error for %s, got: %v", diagnostics, err)
+		}
+	}
+}
+
+func TestLoadConfig_InvalidSchedule_ShouldError(t *testing.T) {
+	for _, task := range []string{
+		`{"Path": "task", "Schedule": "0 30 25 * * *"}`,
+		`{"Path": "task", "Schedule": "@fortnightly"}`,
+		`{"Path": "task", "Schedule": "@every 500ms"}`,
+		`{"Path": "task", "Schedule": "@daily", "Timezone": "Mars/Olympus_Mons"}`,
+	} {
+		// Arrange
+		testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "ScheduledTasks" : [` + task + `]
+    }`
+		tmpFile := writeTestConfig(t, testConfig)
+		defer os.Remove(tmpFile)
+
+		// Act
+		_, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+		// Assert
+		if err == nil || !strings.Contains(err.Error(), "ScheduledTasks") {
+			t.Errorf("Expected ScheduledTasks error for %s, got: %v", task, err)
+		}
+	}
+}
+
+func TestLoadConfig_InvalidStartupTaskOnFailure_ShouldError(t *testing.T) {
+	for _, task := range []string{
+		`{"Path": "task", "OnFailure": "retry"}`,
+		`{"Path": "task", "OnFailure": "abort", "Async": true}`,
+		`{"Path": "task", "OnFailure": "task"}`,
+		`{"Path": "task", "Retries": -1}`,
+	} {
+		// Arrange
+		testConfig := `
+    {
+        "ServiceDescription" : {
+            "DisplayName" : "My Service"
+        },
+        "StartupTasks" : [` + task + `]
+    }`
+		tmpFile := writeTestConfig(t, testConfig)
+		defer os.Remove(tmpFile)
+
+		// Act
+		_, err := config.LoadConfig(tmpFile, config.ReplacementVars{})
+
+		// Assert
+		if err == nil || !strings.Contains(err.Error(), "StartupTasks") {
+			t.Errorf("Expected StartupTasks error for %s, got: %v", task, err)
+		}
+	}
+}
+
 func TestLoadConfig_IncompleteConfig_ShouldError(t *testing.T) {
 	// Arrange
 	testConfig := `
@@ -0,0 +1,135 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+// Package control is a local channel for CLI commands (e.g. logs) to talk to
+// the running service wrapper. The wrapper serves HTTP on a loopback address
+// and writes the address, along with a random access token, to a control file
+// only readable by the wrapper's user (and administrators on Windows).
+package control
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const tokenHeader = "X-Silver-Token"
+
+// Info is the content of the control file.
+type Info struct {
+	Address string
+	Token   string
+	PID     int
+}
+
+// Server is the wrapper's end of the control channel.
+type Server struct {
+	file     string
+	info     Info
+	listener net.Listener
+	server   *http.Server
+}
+
+// Start serves handler on a loopback address and writes the control file.
+func Start(file string, handler http.Handler) (*Server, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		file:     file,
+		info:     Info{Address: ln.Addr().String(), Token: hex.EncodeToString(token), PID: os.Getpid()},
+		listener: ln,
+	}
+	s.server = &http.Server{
+		Handler:           s.authorize(handler),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := s.writeFile(); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	go func() {
+		_ = s.server.Serve(ln)
+	}()
+	return s, nil
+}
+
+func (s *Server) authorize(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(tokenHeader) != s.info.Token {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// writeFile atomically writes the control file so clients never read a partial file.
+func (s *Server) writeFile() error {
+	b, err := json.Marshal(s.info)
+	if err != nil {
+		return err
+	}
+	tmp := s.file + ".tmp"
+	_ = os.Remove(tmp)
+	if err := writePrivateFile(tmp, b); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.file)
+}
+
+// Close stops the server and removes the control file.
+func (s *Server) Close() error {
+	_ = os.Remove(s.file)
+	return s.server.Close()
+}
+
+// Get requests path from the running wrapper. The caller must close the
+// response body.
+func Get(file, path string, query url.Values) (*http.Response, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("the service is not running")
+		}
+		return nil, err
+	}
+	info := Info{}
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("invalid control file %s: %v", file, err)
+	}
+	u := url.URL{Scheme: "http", Host: info.Address, Path: path, RawQuery: query.Encode()}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(tokenHeader, info.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to contact the service: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
@@ -0,0 +1,63 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+package control_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/papercutsoftware/silver/service/control"
+)
+
+func TestControl_GetWithToken(t *testing.T) {
+	// Arrange
+	file := filepath.Join(t.TempDir(), "test.control")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Hello " + r.URL.Query().Get("name")))
+	})
+	server, err := control.Start(file, mux)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	resp, err := control.Get(file, "/hello", url.Values{"name": {"World"}})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "Hello World" {
+		t.Errorf("Unexpected response: %s", body)
+	}
+
+	// Requests without the token are refused
+	b, _ := os.ReadFile(file)
+	info := control.Info{}
+	_ = json.Unmarshal(b, &info)
+	resp, err = http.Get("http://" + info.Address + "/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected unauthorized without the token, got %d", resp.StatusCode)
+	}
+
+	server.Close()
+	if _, err := control.Get(file, "/hello", nil); err == nil {
+		t.Errorf("Expected an error once the server is closed")
+	}
+}
@@ -0,0 +1,16 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+//go:build !windows
+
+package control
+
+import "os"
+
+// writePrivateFile writes a file only the current user can read.
+func writePrivateFile(name string, data []byte) error {
+	return os.WriteFile(name, data, 0600)
+}
@@ -0,0 +1,61 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package control
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// writePrivateFile writes a file only the current user and administrators can
+// read. File modes don't restrict access on Windows, so the file is created
+// empty and given a protected DACL (nothing inherited from the folder) before
+// the data is written. Administrators are included so an elevated CLI can
+// reach a wrapper running as LocalSystem.
+func writePrivateFile(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	if err := restrictToOwner(name); err != nil {
+		_ = os.Remove(name)
+		return err
+	}
+	return os.WriteFile(name, data, 0600)
+}
+
+func restrictToOwner(name string) error {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return err
+	}
+	admins, err := windows.CreateWellKnownSid(windows.WinBuiltinAdministratorsSid)
+	if err != nil {
+		return err
+	}
+	var entries []windows.EXPLICIT_ACCESS
+	for _, sid := range []*windows.SID{user.User.Sid, admins} {
+		entries = append(entries, windows.EXPLICIT_ACCESS{
+			AccessPermissions: windows.GENERIC_ALL,
+			AccessMode:        windows.GRANT_ACCESS,
+			Inheritance:       windows.NO_INHERITANCE,
+			Trustee: windows.TRUSTEE{
+				TrusteeForm:  windows.TRUSTEE_IS_SID,
+				TrusteeValue: windows.TrusteeValueFromSID(sid),
+			},
+		})
+	}
+	acl, err := windows.ACLFromEntries(entries, nil)
+	if err != nil {
+		return err
+	}
+	return windows.SetNamedSecurityInfo(name, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, acl, nil)
+}
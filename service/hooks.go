@@ -0,0 +1,169 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/lib/pathutils"
+	"github.com/papercutsoftware/silver/lib/procmngt"
+	"github.com/papercutsoftware/silver/service/config"
+	"github.com/papercutsoftware/silver/service/svcutil"
+)
+
+const (
+	// How long shutdown waits for event handlers still running
+	eventHandlerShutdownWait = 10 * time.Second
+	// Output of a failed handler command that's logged
+	eventHandlerMaxOutput = 4096
+)
+
+// eventMessage is the JSON passed to event handlers.
+type eventMessage struct {
+	svcutil.Event
+	Wrapper      string // The wrapper's service name
+	Host         string
+	DurationSecs float64 `json:",omitempty"`
+}
+
+// dispatchEvent passes the event to the handlers registered for it. Handlers
+// run in the background so services aren't held up by slow webhooks.
+func dispatchEvent(ctx *context, e svcutil.Event) {
+	var body []byte
+	for _, h := range ctx.currentConf().EventHandlers {
+		if !handlesEvent(h, e.Type) {
+			continue
+		}
+		if body == nil {
+			body = eventJSON(e)
+		}
+		ctx.eventHandlers.Add(1)
+		go func(h config.EventHandler) {
+			defer ctx.eventHandlers.Done()
+			var err error
+			if h.WebhookURL != "" {
+				err = postEvent(ctx.shutdown, h, body)
+			} else {
+				err = runEventCommand(h, e.Type, body)
+			}
+			if err != nil {
+				logging.Errorf(ctx.errorLogger, "ERROR: Event handler for '%s' (%s) failed: %v", e.Type, e.Name, err)
+			} else {
+				logging.Debugf(ctx.logger, "Event handler for '%s' (%s) completed", e.Type, e.Name)
+			}
+		}(h)
+	}
+}
+
+func handlesEvent(h config.EventHandler, eventType string) bool {
+	for _, e := range h.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func eventJSON(e svcutil.Event) []byte {
+	host, _ := os.Hostname()
+	b, _ := json.Marshal(eventMessage{
+		Event:        e,
+		Wrapper:      serviceName(),
+		Host:         host,
+		DurationSecs: e.Duration.Seconds(),
+	})
+	return b
+}
+
+// runEventCommand runs the handler's command with the event JSON on STDIN.
+func runEventCommand(h config.EventHandler, eventType string, body []byte) error {
+	output := &svcutil.LimitedBuffer{Max: eventHandlerMaxOutput}
+	execConf := procmngt.ExecConfig{
+		Path:             pathutils.FindLastFile(h.Path),
+		Args:             h.Args,
+		ExecTimeout:      time.Duration(h.TimeoutSecs) * time.Second,
+		GracefulShutDown: 5 * time.Second,
+		Stdin:            bytes.NewReader(body),
+		Stdout:           output,
+		Stderr:           output,
+		Env:              append(os.Environ(), "SILVER_EVENT="+eventType),
+	}
+	exitCode, err := procmngt.NewExecutable(execConf).Execute(nil)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("%s exited with code %d: %s", h.Path, exitCode, bytes.TrimSpace(output.Bytes()))
+	}
+	return nil
+}
+
+// postEvent POSTs the event JSON to the handler's webhook, retrying with
+// backoff on failure. Retries are abandoned once the wrapper is shutting down.
+func postEvent(shutdown chan struct{}, h config.EventHandler, body []byte) error {
+	client := &http.Client{Timeout: time.Duration(h.TimeoutSecs) * time.Second}
+	var delay time.Duration
+	if h.RetryDelaySecs > 0 {
+		delay = time.Duration(h.RetryDelaySecs) * time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		err := postEventOnce(client, h, body)
+		if err == nil || attempt >= h.Retries {
+			return err
+		}
+		select {
+		case <-shutdown:
+			return fmt.Errorf("%v (not retried, shutting down)", err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func postEventOnce(client *http.Client, h config.EventHandler, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", h.WebhookURL, resp.Status)
+	}
+	return nil
+}
+
+// waitForEventHandlers gives handlers still running (e.g. notifying that
+// services stopped) a chance to finish before we exit.
+func waitForEventHandlers(ctx *context) {
+	done := make(chan struct{})
+	go func() {
+		ctx.eventHandlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(eventHandlerShutdownWait):
+		logging.Warnf(ctx.logger, "WARNING: Event handlers still running at shutdown")
+	}
+}
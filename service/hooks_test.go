@@ -0,0 +1,119 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/papercutsoftware/silver/service/config"
+	"github.com/papercutsoftware/silver/service/svcutil"
+)
+
+func TestHooks_WebhookRetriesUntilAccepted(t *testing.T) {
+	// Arrange
+	var lock sync.Mutex
+	var attempts int
+	var received eventMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("expected the configured header, got %q", r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid event JSON: %v", err)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("TEST_WEBHOOK_TOKEN", "test-token")
+
+	h := config.EventHandler{
+		Events:         []string{svcutil.EventCrashLimitExceeded},
+		WebhookURL:     server.URL,
+		Headers:        map[string]string{"Authorization": "Bearer ${TEST_WEBHOOK_TOKEN}"},
+		Retries:        3,
+		RetryDelaySecs: -1, // Retry immediately
+		TimeoutSecs:    5,
+	}
+	event := svcutil.Event{Type: svcutil.EventCrashLimitExceeded, Name: "my-app", Error: "Max crash count exceeded."}
+
+	// Act
+	err := postEvent(nil, h, eventJSON(event))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected the webhook to succeed after retries: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if received.Type != svcutil.EventCrashLimitExceeded || received.Name != "my-app" || received.Wrapper == "" {
+		t.Errorf("unexpected event received: %+v", received)
+	}
+}
+
+func TestHooks_WebhookGivesUpAfterRetries(t *testing.T) {
+	// Arrange
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	h := config.EventHandler{WebhookURL: server.URL, Retries: 2, RetryDelaySecs: -1, TimeoutSecs: 5}
+
+	// Act
+	err := postEvent(nil, h, []byte("{}"))
+
+	// Assert
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if attempts != 3 {
+		t.Errorf("expected the first attempt and 2 retries, got %d", attempts)
+	}
+}
+
+func TestHooks_WebhookStopsRetryingAtShutdown(t *testing.T) {
+	// Arrange
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	h := config.EventHandler{WebhookURL: server.URL, Retries: 3, RetryDelaySecs: 60, TimeoutSecs: 5}
+	shutdown := make(chan struct{})
+	close(shutdown)
+	start := time.Now()
+
+	// Act
+	err := postEvent(shutdown, h, []byte("{}"))
+
+	// Assert
+	if err == nil || attempts.Load() != 1 {
+		t.Errorf("expected a single failed attempt, got %d: %v", attempts.Load(), err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("expected the retry delay to be cut short by shutdown")
+	}
+}
@@ -0,0 +1,159 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/service/control"
+)
+
+const defaultLogsLines = 100
+
+func controlFileName() string {
+	return serviceName() + ".control"
+}
+
+// outputBuffer returns the recent output buffer for a service. Buffers are
+// kept across reloads so the output from before the reload is still available,
+// and resized if the service's OutputBufferLines changed.
+func outputBuffer(ctx *context, name string, size int) *logging.RingBuffer {
+	ctx.outputBuffersLock.Lock()
+	defer ctx.outputBuffersLock.Unlock()
+	if ctx.outputBuffers == nil {
+		ctx.outputBuffers = make(map[string]*logging.RingBuffer)
+	}
+	buffer, ok := ctx.outputBuffers[name]
+	if !ok {
+		buffer = logging.NewRingBuffer(size)
+		ctx.outputBuffers[name] = buffer
+	} else {
+		buffer.Resize(size)
+	}
+	return buffer
+}
+
+// findOutputBuffer finds a service's buffer by name, with or without the
+// executable's extension.
+func findOutputBuffer(ctx *context, name string) (*logging.RingBuffer, []string) {
+	ctx.outputBuffersLock.Lock()
+	defer ctx.outputBuffersLock.Unlock()
+	var names []string
+	for n, buffer := range ctx.outputBuffers {
+		if n == name || strings.TrimSuffix(n, ".exe") == name {
+			return buffer, nil
+		}
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, names
+}
+
+func startControl(ctx *context) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/logs", func(w http.ResponseWriter, r *http.Request) {
+		serveLogs(ctx, w, r)
+	})
+	server, err := control.Start(controlFileName(), mux)
+	if err != nil {
+		logging.Errorf(ctx.errorLogger, "ERROR: Unable to start the control channel: %v", err)
+		return
+	}
+	ctx.control = server
+}
+
+func stopControl(ctx *context) {
+	if ctx.control != nil {
+		_ = ctx.control.Close()
+		ctx.control = nil
+	}
+}
+
+func serveLogs(ctx *context, w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("service")
+	buffer, names := findOutputBuffer(ctx, name)
+	if buffer == nil {
+		http.Error(w, fmt.Sprintf("Unknown service '%s'. Services: %s", name, strings.Join(names, ", ")), http.StatusNotFound)
+		return
+	}
+	lines, err := strconv.Atoi(r.URL.Query().Get("lines"))
+	if err != nil {
+		lines = defaultLogsLines
+	}
+	follow := r.URL.Query().Get("follow") == "true"
+
+	// Subscribe first so nothing is missed between the history and following
+	var records <-chan logging.Record
+	if follow {
+		var cancel func()
+		records, cancel = buffer.Subscribe()
+		defer cancel()
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, record := range buffer.Last(lines) {
+		writeLogLine(w, record)
+	}
+	if !follow {
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case record := <-records:
+			writeLogLine(w, record)
+		case <-r.Context().Done():
+			return
+		case <-ctx.shutdown:
+			return
+		}
+	}
+}
+
+func writeLogLine(w io.Writer, r logging.Record) {
+	_, _ = fmt.Fprintf(w, "%s %s|%s\n", r.Time.Format("2006-01-02 15:04:05"), strings.ToUpper(r.Stream), r.Message)
+}
+
+// printLogs prints a service's recent output from the running wrapper,
+// optionally following new output (-f).
+func printLogs(args []string) int {
+	query := url.Values{}
+	for _, arg := range args {
+		switch arg {
+		case "-f", "--follow":
+			query.Set("follow", "true")
+		default:
+			query.Set("service", arg)
+		}
+	}
+	if query.Get("service") == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: Usage: %s logs <service> [-f]\n", exeName())
+		return 1
+	}
+	resp, err := control.Get(controlFileName(), "/logs", query)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -8,40 +8,72 @@ package main
 import (
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
+	_ "time/tzdata" // Scheduled task time zones on systems without zone data (Windows)
 
 	"github.com/kardianos/service"
+	"github.com/papercutsoftware/silver/lib/fswatch"
 	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/lib/metrics"
 	"github.com/papercutsoftware/silver/lib/osutils"
 	"github.com/papercutsoftware/silver/lib/pathutils"
 	"github.com/papercutsoftware/silver/service/cmdutil"
 	"github.com/papercutsoftware/silver/service/config"
+	"github.com/papercutsoftware/silver/service/control"
 	"github.com/papercutsoftware/silver/service/svcutil"
 	"github.com/robfig/cron"
 )
 
 const (
 	defaultRefreshPoll = 10 * time.Second
+	versionFileName    = ".version" // Written by the updater
 )
 
 type context struct {
-	conf         *config.Config
+	conf         *config.Config // Replaced on reload. Use currentConf outside the lifecycle lock
+	confLock     sync.RWMutex
+	svc          service.Service
 	terminate    chan struct{}
+	shutdown     chan struct{}
+	lifecycle    sync.Mutex
 	logger       *log.Logger
 	errorLogger  *log.Logger
 	runningGroup sync.WaitGroup
 	cronManager  *cron.Cron
+	status       *statusTracker
+	control      *control.Server
+	version      string // Installed version, as last read from the version file
+
+	metrics       *metrics.Registry
+	metricsServer *http.Server
+	eventHandlers sync.WaitGroup
+
+	outputBuffers     map[string]*logging.RingBuffer
+	outputBuffersLock sync.Mutex
+
+	outputLogs     []*log.Logger // Services' and tasks' own log files, closed on reload
+	outputLogsLock sync.Mutex
 }
 
 func main() {
 	os.Exit(run())
 }
 
+// currentConf returns the config for code that can run alongside a reload,
+// such as event handling and the service goroutines.
+func (ctx *context) currentConf() *config.Config {
+	ctx.confLock.RLock()
+	defer ctx.confLock.RUnlock()
+	return ctx.conf
+}
+
 func run() (exitCode int) {
 	err := os.Chdir(exeFolder())
 	if err != nil {
@@ -72,6 +104,10 @@ func run() (exitCode int) {
 	case "validate":
 		fmt.Println("Config is valid")
 		return 0
+	case "status":
+		return printStatus(ctx)
+	case "logs":
+		return printLogs(actionArgs)
 	case "install":
 		if err = writeProxyConf(); err != nil {
 			_, _ = fmt.Fprintf(os.Stderr, "WARNING: Unable to store HTTP Proxy settings: %v\n", err)
@@ -99,30 +135,15 @@ func osServiceControl(ctx *context) int {
 	serviceName := serviceName()
 
 	// Setup log file out
-	logFile := ctx.conf.ServiceConfig.LogFile
-	maxSize := int64(ctx.conf.ServiceConfig.LogFileMaxSizeMb) * 1024 * 1024
-	if logFile == "" {
-		logFile = serviceName + ".log"
-	}
-	if logFile == "os.stdout" {
-		ctx.logger = logging.NewConsoleLogger(ctx.conf.ServiceConfig.LogFileTimestampFormat)
-		ctx.errorLogger = logging.NewConsoleErrorLogger(ctx.conf.ServiceConfig.LogFileTimestampFormat)
-	} else {
-		ctx.logger = logging.NewFileLoggerWithMaxSize(logFile, ctx.conf.ServiceConfig.UserName, maxSize, ctx.conf.ServiceConfig.LogFileMaxBackupFiles, ctx.conf.ServiceConfig.LogFileTimestampFormat)
-		ctx.errorLogger = ctx.logger // use the same output for both stdout and errors
+	logFileConf := ctx.conf.ServiceConfig.LogFileConfig
+	if logFileConf.LogFile == "" {
+		logFileConf.LogFile = serviceName + ".log"
 	}
+	ctx.logger, ctx.errorLogger = newLoggers(ctx.conf, logFileConf)
+	applyLogSettings(ctx.conf)
 
 	// Setup service
-	svcConfig := &service.Config{
-		Name:        serviceName,
-		DisplayName: ctx.conf.ServiceDescription.DisplayName,
-		Description: ctx.conf.ServiceDescription.Description,
-		UserName:    ctx.conf.ServiceConfig.UserName,
-		Option:      service.KeyValue{"UserService": ctx.conf.ServiceConfig.UserLevel},
-	}
-
-	osService := &osService{ctx: ctx}
-	svc, err := service.New(osService, svcConfig)
+	svc, err := newOSService(ctx)
 	if err != nil {
 		fmt.Printf("ERROR: Invalid service config: %v\n", err)
 		return 1
@@ -150,16 +171,108 @@ func osServiceControl(ctx *context) int {
 	return 0
 }
 
+// newLoggers creates the standard and error loggers for a log file config.
+func newLoggers(conf *config.Config, logFileConf config.LogFileConfig) (logger, errorLogger *log.Logger) {
+	logConf := logging.Config{
+		File:           logFileConf.LogFile,
+		Owner:          conf.ServiceConfig.UserName,
+		MaxSize:        logFileConf.LogFileMaxSizeMb * 1024 * 1024,
+		MaxBackupFiles: logFileConf.LogFileMaxBackupFiles,
+		TimeFormat:     conf.ServiceConfig.LogFileTimestampFormat,
+		Format:         conf.ServiceConfig.LogFormat,
+		Rotation:       logFileConf.LogFileRotation,
+		Compress:       logFileConf.LogFileCompress,
+		MaxAge:         time.Duration(logFileConf.LogFileMaxAgeDays) * 24 * time.Hour,
+		MaxTotalSize:   logFileConf.LogFileMaxTotalSizeMb * 1024 * 1024,
+		Tag:            serviceName(),
+	}
+	logger = logging.NewLogger(logConf)
+	if logConf.File == "os.stdout" {
+		logConf.File = "os.stderr"
+		return logger, logging.NewLogger(logConf)
+	}
+	return logger, logger // use the same output for both stdout and errors
+}
+
+// applyLogSettings applies the log level and redaction patterns, which may
+// change on reload. They're validated when the config is loaded.
+func applyLogSettings(conf *config.Config) {
+	_ = logging.SetLevel(conf.ServiceConfig.LogLevel)
+	redactor, _ := logging.NewRedactor(conf.LogRedact.Patterns, conf.LogRedact.Replacement)
+	if len(conf.LogRedact.Patterns) == 0 {
+		redactor = nil
+	}
+	logging.SetRedactor(redactor)
+}
+
+// outputLoggers returns the loggers for a service or task's output. Output
+// goes to the main log unless the service or task has its own log file.
+func outputLoggers(ctx *context, logFileConf config.LogFileConfig) (logger, errorLogger *log.Logger) {
+	if logFileConf.LogFile == "" {
+		return ctx.logger, ctx.errorLogger
+	}
+	conf := ctx.currentConf()
+	// Rotation settings not set on the service or task are inherited from the main log
+	main := conf.ServiceConfig.LogFileConfig
+	if logFileConf.LogFileMaxSizeMb == 0 {
+		logFileConf.LogFileMaxSizeMb = main.LogFileMaxSizeMb
+	}
+	if logFileConf.LogFileRotation == "" {
+		logFileConf.LogFileRotation = main.LogFileRotation
+	}
+	// Retention is inherited as a whole, so the main log's backup count
+	// doesn't cut short the service or task's own age or size retention
+	if logFileConf.LogFileMaxBackupFiles == 0 && logFileConf.LogFileMaxAgeDays == 0 && logFileConf.LogFileMaxTotalSizeMb == 0 {
+		logFileConf.LogFileMaxBackupFiles = main.LogFileMaxBackupFiles
+		logFileConf.LogFileMaxAgeDays = main.LogFileMaxAgeDays
+		logFileConf.LogFileMaxTotalSizeMb = main.LogFileMaxTotalSizeMb
+	}
+	// As for the main log, keep one backup if there is no retention
+	if logFileConf.LogFileMaxBackupFiles == 0 && logFileConf.LogFileMaxAgeDays == 0 && logFileConf.LogFileMaxTotalSizeMb == 0 {
+		logFileConf.LogFileMaxBackupFiles = 1
+	}
+	logFileConf.LogFileCompress = logFileConf.LogFileCompress || main.LogFileCompress
+	logger, errorLogger = newLoggers(conf, logFileConf)
+	ctx.outputLogsLock.Lock()
+	ctx.outputLogs = append(ctx.outputLogs, logger, errorLogger)
+	ctx.outputLogsLock.Unlock()
+	return logger, errorLogger
+}
+
+// closeOutputLoggers closes the services' and tasks' own log files once
+// they've stopped, rather than leaving it to the garbage collector.
+func closeOutputLoggers(ctx *context) {
+	ctx.outputLogsLock.Lock()
+	defer ctx.outputLogsLock.Unlock()
+	for _, logger := range ctx.outputLogs {
+		logging.CloseLogger(logger)
+	}
+	ctx.outputLogs = nil
+}
+
+func newOSService(ctx *context) (service.Service, error) {
+	svcConfig := &service.Config{
+		Name:        serviceName(),
+		DisplayName: ctx.conf.ServiceDescription.DisplayName,
+		Description: ctx.conf.ServiceDescription.Description,
+		UserName:    ctx.conf.ServiceConfig.UserName,
+		Option:      service.KeyValue{"UserService": ctx.conf.ServiceConfig.UserLevel},
+	}
+	return service.New(&osService{ctx: ctx}, svcConfig)
+}
+
 func printUsage(svcDisplayName, svcDesc string) {
 	fmt.Printf("%s (%s)\n", svcDisplayName,
 		serviceName())
 	fmt.Printf("%s\n\n", svcDesc)
 	fmt.Printf("Usage:\n")
-	fmt.Printf("%s [install|uninstall|start|stop|command|validate|run|help] [command-name]\n", exeName())
+	fmt.Printf("%s [install|uninstall|start|stop|status|logs|command|validate|run|help] [command-name|service]\n", exeName())
 	fmt.Printf("  install   - Install the service.\n")
 	fmt.Printf("  uninstall - Remove/uninstall the service.\n")
 	fmt.Printf("  start     - Start an installed service.\n")
 	fmt.Printf("  stop      - Stop an installed service.\n")
+	fmt.Printf("  status    - Show the service status.\n")
+	fmt.Printf("  logs      - Show a service's recent output [service] (-f to follow).\n")
 	fmt.Printf("  validate  - Test the configuration file.\n")
 	fmt.Printf("  run       - Run service on in command-line mode.\n")
 	fmt.Printf("  command   - Run a command [command-name].\n")
@@ -183,9 +296,12 @@ func loadConf() (conf *config.Config, err error) {
 		include = pathutils.FindLastFile(include)
 		conf, err = config.MergeInclude(*conf, include, vars)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("include %s: %v", include, err)
 		}
 	}
+	if err = conf.Validate(); err != nil {
+		return nil, err
+	}
 	return conf, err
 }
 
@@ -270,7 +386,7 @@ type osService struct {
 
 func (o *osService) Start(s service.Service) error {
 	msg := fmt.Sprintf("Service '%s' started.", serviceName())
-	o.ctx.logger.Print(msg)
+	logging.Infof(o.ctx.logger, "%s", msg)
 	sysLogger, err := s.Logger(nil)
 	if err == nil {
 		_ = sysLogger.Info(msg)
@@ -280,11 +396,21 @@ func (o *osService) Start(s service.Service) error {
 
 	proxy := os.Getenv("SILVER_HTTP_PROXY")
 	if proxy != "" {
-		o.ctx.logger.Printf("Proxy set to: '%s'", proxy)
-	}
-
+		logging.Debugf(o.ctx.logger, "Proxy set to: '%s'", proxy)
+	}
+
+	o.ctx.svc = s
+	o.ctx.shutdown = make(chan struct{})
+	o.ctx.status = &statusTracker{file: statusFileName(o.ctx)}
+	o.ctx.version = osutils.ReadStringFromFile(versionFileName, "")
+	o.ctx.status.setVersion(o.ctx.version)
+	o.ctx.status.write()
+	go o.ctx.status.run(o.ctx.shutdown)
+	startControl(o.ctx)
+	o.ctx.metrics = newMetrics()
+	startMetrics(o.ctx)
 	doStart(o.ctx)
-	go watchForReload(o.ctx)
+	go watchSignalFiles(o.ctx)
 
 	return nil
 }
@@ -295,23 +421,35 @@ func doStart(ctx *context) {
 		_ = os.Remove(sf)
 	}
 	ctx.terminate = make(chan struct{})
-	execStartupTasks(ctx)
+	if !execStartupTasks(ctx) {
+		// Stop via the service manager, outside the lifecycle lock we may hold
+		go requestShutdown(ctx)
+		return
+	}
 	setupScheduledTasks(ctx)
 	startServices(ctx)
 }
 
 func (o *osService) Stop(s service.Service) error {
-	o.ctx.logger.Printf("Stopping '%s' service...", serviceName())
+	logging.Infof(o.ctx.logger, "Stopping '%s' service...", serviceName())
 
+	o.ctx.lifecycle.Lock()
+	close(o.ctx.shutdown)
 	doStop(o.ctx)
+	closeOutputLoggers(o.ctx)
+	o.ctx.lifecycle.Unlock()
 
-	pidFile := o.ctx.conf.ServiceConfig.PidFile
+	pidFile := o.ctx.currentConf().ServiceConfig.PidFile
 	if pidFile != "" {
 		_ = os.Remove(pidFile)
 	}
+	o.ctx.status.remove()
+	stopControl(o.ctx)
+	stopMetrics(o.ctx)
+	waitForEventHandlers(o.ctx)
 
 	msg := fmt.Sprintf("Stopped '%s' service.", serviceName())
-	o.ctx.logger.Print(msg)
+	logging.Infof(o.ctx.logger, "%s", msg)
 
 	sysLogger, err := s.Logger(nil)
 	if err == nil {
@@ -324,7 +462,7 @@ func (o *osService) Stop(s service.Service) error {
 
 func stopFileName(ctx *context) string {
 	stopFile := ctx.conf.ServiceConfig.StopFile
-	if stopFile != "disabled" {
+	if stopFile == "disabled" {
 		return ""
 	}
 	return stopFile
@@ -347,51 +485,205 @@ func doStop(ctx *context) {
 	ctx.runningGroup.Wait()
 }
 
-func watchForReload(ctx *context) {
-	f := ctx.conf.ServiceConfig.ReloadFile
+// watchSignalFiles acts on the stop and reload files (and config changes)
+// until the service is shut down.
+func watchSignalFiles(ctx *context) {
 	for {
-		// FIXME: File system notification rather than polling?
-		time.Sleep(defaultRefreshPoll)
-		if _, err := os.Stat(f); err == nil {
-			if err := os.Remove(f); err == nil {
-				ctx.logger.Printf("Reload requested. Services will now restart.")
-				doStop(ctx)
-				time.Sleep(time.Second)
-				// Reload config
-				ctx.conf, _ = loadConf()
-				doStart(ctx)
-			}
+		reason, stop := waitForSignal(ctx)
+		if reason == "" {
+			return
+		}
+		if stop {
+			logging.Infof(ctx.logger, "%s Service will now shut down.", reason)
+			requestShutdown(ctx)
+			return
+		}
+		// Validate the new config before we stop anything
+		conf, err := loadConf()
+		ctx.status.setReload(err)
+		if err != nil {
+			logging.Errorf(ctx.errorLogger, "ERROR: %s Reload rejected, invalid config - %v", reason, err)
+			logging.Errorf(ctx.errorLogger, "ERROR: Services will continue to run with the previous config.")
+			handleEvent(ctx, svcutil.Event{Type: svcutil.EventReload, Time: time.Now(), Name: serviceName(), Reason: reason, Error: err.Error()})
+			continue
+		}
+		logging.Infof(ctx.logger, "%s Services will now restart.", reason)
+		ctx.lifecycle.Lock()
+		if svcutil.IsClosed(ctx.shutdown) {
+			// The service stopped while we waited for the lock
+			ctx.lifecycle.Unlock()
+			return
 		}
+		doStop(ctx)
+		closeOutputLoggers(ctx)
+		time.Sleep(time.Second)
+		ctx.confLock.Lock()
+		ctx.conf = conf
+		ctx.confLock.Unlock()
+		applyLogSettings(conf)
+		startMetrics(ctx)
+		// The updater requests a reload once it has installed an update
+		version := osutils.ReadStringFromFile(versionFileName, "")
+		if version != ctx.version {
+			ctx.version = version
+			ctx.status.setVersion(version)
+			handleEvent(ctx, svcutil.Event{Type: svcutil.EventUpdateApplied, Time: time.Now(), Name: serviceName(), Version: version})
+		}
+		handleEvent(ctx, svcutil.Event{Type: svcutil.EventReload, Time: time.Now(), Name: serviceName(), Reason: reason})
+		doStart(ctx)
+		ctx.lifecycle.Unlock()
 	}
 }
 
-func execStartupTasks(ctx *context) {
-	ctx.logger.Printf("Starting %d startup tasks.", len(ctx.conf.StartupTasks))
-	for _, task := range ctx.conf.StartupTasks {
-		runTask := func(task config.StartupTask) {
-			ctx.runningGroup.Add(1)
-			defer ctx.runningGroup.Done()
-			taskName := path.Base(task.Path)
-			taskConfig := createTaskConfig(ctx, task.Task)
-			if exitCode, err := svcutil.ExecuteTask(ctx.terminate, taskConfig); err != nil {
-				ctx.errorLogger.Printf("ERROR: Startup task '%s' reported: %v", taskName, err)
-			} else {
-				ctx.logger.Printf("Startup task '%s' finished with exit code %d", taskName, exitCode)
+// waitForSignal blocks until the stop or reload file is created or, if
+// enabled, the config (or any of its resolved include files) changes and
+// settles. An empty reason is returned if the service is shutting down.
+func waitForSignal(ctx *context) (reason string, stop bool) {
+	stopFile := stopFileName(ctx)
+	reloadFile := ctx.conf.ServiceConfig.ReloadFile
+	loaded := configFiles(ctx.conf)
+	globDirs := includeGlobDirs(ctx.conf)
+	files := append([]string{reloadFile}, loaded...)
+	if stopFile != "" {
+		files = append(files, stopFile)
+	}
+	watcher := fswatch.New(append(files, globDirs...), defaultRefreshPoll)
+	defer watcher.Close()
+
+	debounceWindow := time.Duration(ctx.conf.ServiceConfig.ReloadDebounceSecs) * time.Second
+	var debounce <-chan time.Time
+	includesOnly := false // Only glob include directories changed, which may not matter
+	for {
+		select {
+		case <-ctx.shutdown:
+			// We create the stop file ourselves while stopping
+			return "", false
+		default:
+		}
+		if stopFile != "" && osutils.FileExists(stopFile) {
+			return fmt.Sprintf("Stop file '%s' detected.", stopFile), true
+		}
+		if _, err := os.Stat(reloadFile); err == nil {
+			if err := os.Remove(reloadFile); err == nil {
+				return "Reload requested.", false
 			}
 		}
+		select {
+		case changed := <-watcher.Events:
+			if changed != reloadFile && changed != stopFile && ctx.conf.ServiceConfig.ReloadOnConfigChange {
+				// Wait for the changes to settle (e.g. an update writing several includes)
+				includesOnly = (debounce == nil || includesOnly) && config.Contains(globDirs, changed)
+				debounce = time.After(debounceWindow)
+			}
+		case <-debounce:
+			debounce = nil
+			// Other files come and go in the include directories
+			if includesOnly && strings.Join(configFiles(ctx.conf), "\n") == strings.Join(loaded, "\n") {
+				continue
+			}
+			return "Configuration change detected.", false
+		case <-ctx.shutdown:
+			return "", false
+		}
+	}
+}
+
+// requestShutdown stops the wrapper via the same graceful path as an OS
+// initiated stop. When installed, the OS service manager must do the stop,
+// otherwise it will treat our exit as a crash and restart us.
+func requestShutdown(ctx *context) {
+	if !service.Interactive() && ctx.svc != nil {
+		err := ctx.svc.Stop()
+		if err == nil {
+			return
+		}
+		logging.Errorf(ctx.errorLogger, "ERROR: Unable to stop service via the service manager: %v", err)
+	}
+	if err := osutils.ProcessSignalQuit(os.Getpid()); err != nil {
+		logging.Errorf(ctx.errorLogger, "ERROR: Unable to signal shutdown: %v", err)
+	}
+}
+
+// configFiles returns the config file and the include files it resolves to.
+func configFiles(conf *config.Config) []string {
+	files := []string{getConfigFilePath()}
+	for _, include := range conf.Include {
+		files = append(files, pathutils.FindLastFile(include))
+	}
+	return files
+}
+
+// includeGlobDirs returns the directories where new matches of glob includes
+// (e.g. a new v* directory) would appear, so they can be watched.
+func includeGlobDirs(conf *config.Config) []string {
+	var dirs []string
+	for _, include := range conf.Include {
+		dir := include
+		for hasGlobMeta(dir) {
+			dir = filepath.Dir(dir)
+		}
+		if dir != include {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
+
+// execStartupTasks runs the startup tasks, returning false if a failed task's
+// OnFailure policy aborted startup.
+func execStartupTasks(ctx *context) bool {
+	logging.Infof(ctx.logger, "Starting %d startup tasks.", len(ctx.conf.StartupTasks))
+	runTask := func(task config.Task) bool {
+		ctx.runningGroup.Add(1)
+		defer ctx.runningGroup.Done()
+		taskName := path.Base(task.Path)
+		taskConfig := createTaskConfig(ctx, task)
+		exitCode, err := svcutil.ExecuteTask(ctx.terminate, taskConfig)
+		if err != nil {
+			logging.Errorf(ctx.errorLogger, "ERROR: Startup task '%s' reported: %v", taskName, err)
+			return false
+		}
+		logging.Infof(ctx.logger, "Startup task '%s' finished with exit code %d", taskName, exitCode)
+		return true
+	}
+	for _, task := range ctx.conf.StartupTasks {
 		if task.Async {
-			go runTask(task)
-		} else {
-			if task.StartupDelaySecs > 0 || task.StartupRandomDelaySecs > 0 {
-				ctx.logger.Printf("WARNING: Only Async startup tasks should have startup delays.")
+			go runTask(task.Task)
+			continue
+		}
+		if task.StartupDelaySecs > 0 || task.StartupRandomDelaySecs > 0 {
+			logging.Warnf(ctx.logger, "WARNING: Only Async startup tasks should have startup delays.")
+		}
+		if runTask(task.Task) || svcutil.IsClosed(ctx.terminate) || task.OnFailure == "" || task.OnFailure == "continue" {
+			continue
+		}
+		taskName := path.Base(task.Path)
+		if task.OnFailure == "task" {
+			logging.Infof(ctx.logger, "Startup task '%s' failed. Running '%s'.", taskName, path.Base(task.FailureTask.Path))
+			if runTask(*task.FailureTask) || svcutil.IsClosed(ctx.terminate) {
+				continue
 			}
-			runTask(task)
 		}
+		logging.Errorf(ctx.errorLogger, "ERROR: Startup task '%s' failed. Aborting startup, services will not be started.", taskName)
+		handleEvent(ctx, svcutil.Event{Type: svcutil.EventStartupAborted, Time: time.Now(), Name: serviceName(), Reason: taskName})
+		return false
 	}
+	return true
 }
 
 func startServices(ctx *context) {
-	ctx.logger.Printf("Starting %d services.", len(ctx.conf.Services))
+	logging.Infof(ctx.logger, "Starting %d services.", len(ctx.conf.Services))
+	if ctx.status != nil {
+		var names []string
+		for _, srv := range ctx.conf.Services {
+			names = append(names, filepath.Base(pathutils.FindLastFile(srv.Path)))
+		}
+		ctx.status.setServices(names)
+	}
 
 	ctx.runningGroup.Add(len(ctx.conf.Services))
 	for _, srv := range ctx.conf.Services {
@@ -404,28 +696,100 @@ func startServices(ctx *context) {
 			svcConfig.Args = service.Args
 			svcConfig.GracefulShutDown = time.Duration(service.GracefulShutdownTimeoutSecs) * time.Second
 			svcConfig.StartupDelay = time.Duration(service.StartupDelaySecs) * time.Second
+			if service.StopFile != "" {
+				svcConfig.StopFile, _ = filepath.Abs(service.StopFile)
+			}
 			svcConfig.Logger = ctx.logger
 			svcConfig.ErrorLogger = ctx.errorLogger
+			svcConfig.OutputLogger, svcConfig.OutputErrorLogger = outputLoggers(ctx, service.LogFileConfig)
+			svcConfig.OutputContinuation = continuationPattern(service.OutputContinuationPattern)
+			svcConfig.OutputBuffer = outputBuffer(ctx, serviceName, service.OutputBufferLines)
 			svcConfig.CrashConfig = svcutil.CrashConfig{
 				MaxCountPerHour: service.MaxCrashCountPerHour,
 				RestartDelay:    time.Duration(service.RestartDelaySecs) * time.Second,
+				Bundle:          crashBundleConfig(ctx, service),
 			}
+			svcConfig.OnEvent = func(e svcutil.Event) { handleEvent(ctx, e) }
 			if service.MonitorPing != nil {
-				svcConfig.MonitorConfig = svcutil.MonitorConfig{
-					URL:                   service.MonitorPing.URL,
-					StartupDelay:          time.Duration(service.MonitorPing.StartupDelaySecs) * time.Second,
-					Interval:              time.Duration(service.MonitorPing.IntervalSecs) * time.Second,
-					Timeout:               time.Duration(service.MonitorPing.TimeoutSecs) * time.Second,
-					RestartOnFailureCount: service.MonitorPing.RestartOnFailureCount,
+				svcConfig.MonitorConfig = monitorConfig(*service.MonitorPing)
+			}
+			for _, m := range service.Monitors {
+				svcConfig.Monitors = append(svcConfig.Monitors, monitorConfig(m))
+			}
+			svcConfig.MonitorPolicy = service.MonitorPolicy
+			svcConfig.MonitorFlap = svcutil.FlapConfig{
+				Count:  service.MonitorFlapCount,
+				Window: time.Duration(service.MonitorFlapWindowMins) * time.Minute,
+				Action: service.MonitorFlapAction,
+			}
+			if h := service.HangDiagnostics; h != nil {
+				svcConfig.HangDiagnostics = svcutil.DiagnosticsConfig{
+					Action:  h.Action,
+					Path:    h.Path,
+					Args:    h.Args,
+					Dir:     h.Dir,
+					Timeout: time.Duration(h.TimeoutSecs) * time.Second,
 				}
 			}
 			if err := svcutil.ExecuteService(ctx.terminate, svcConfig); err != nil {
-				ctx.errorLogger.Printf("ERROR: Service '%s' reported: %v", serviceName, err)
+				logging.Errorf(ctx.errorLogger, "ERROR: Service '%s' reported: %v", serviceName, err)
 			}
 		}(srv)
 	}
 }
 
+func monitorConfig(m config.MonitorPing) svcutil.MonitorConfig {
+	url := m.URL
+	if strings.HasPrefix(strings.ToLower(url), "exec://") {
+		url = "exec://" + pathutils.FindLastFile(url[len("exec://"):])
+	}
+	return svcutil.MonitorConfig{
+		URL:                   url,
+		Args:                  m.Args,
+		StartupDelay:          time.Duration(m.StartupDelaySecs) * time.Second,
+		Interval:              time.Duration(m.IntervalSecs) * time.Second,
+		Timeout:               time.Duration(m.TimeoutSecs) * time.Second,
+		RestartOnFailureCount: m.RestartOnFailureCount,
+		Echo:                  m.Echo,
+		Send:                  m.Send,
+		Expect:                m.Expect,
+		CertExpiryWarning:     time.Duration(m.CertExpiryWarningDays) * 24 * time.Hour,
+		FileMaxAge:            time.Duration(m.MaxAgeSecs) * time.Second,
+		HTTP: svcutil.HTTPCheck{
+			Method:             m.Method,
+			Headers:            m.Headers,
+			ExpectedStatus:     m.ExpectedStatus,
+			BodyPattern:        m.BodyPattern,
+			JSONPath:           m.JSONPath,
+			JSONValue:          m.JSONValue,
+			Redirects:          m.Redirects,
+			CACertFile:         m.CACertFile,
+			ClientCertFile:     m.ClientCertFile,
+			ClientKeyFile:      m.ClientKeyFile,
+			InsecureSkipVerify: m.InsecureSkipVerify,
+			UnixSocket:         m.UnixSocket,
+		},
+	}
+}
+
+func crashBundleConfig(ctx *context, service config.Service) svcutil.CrashBundleConfig {
+	sc := ctx.currentConf().ServiceConfig
+	if sc.CrashBundleDir == "" {
+		return svcutil.CrashBundleConfig{}
+	}
+	conf := svcutil.CrashBundleConfig{
+		Dir:      sc.CrashBundleDir,
+		Files:    service.CrashFiles,
+		MaxCount: sc.CrashBundleMaxCount,
+		MaxAge:   time.Duration(sc.CrashBundleMaxAgeDays) * 24 * time.Hour,
+		Version:  osutils.ReadStringFromFile(versionFileName, ""),
+	}
+	if sc.CrashBundleMaxFileMb > 0 {
+		conf.MaxFileSize = sc.CrashBundleMaxFileMb * 1024 * 1024
+	}
+	return conf
+}
+
 func createTaskConfig(ctx *context, task config.Task) svcutil.TaskConfig {
 	taskConfig := svcutil.TaskConfig{}
 	taskConfig.Path = pathutils.FindLastFile(task.Path)
@@ -435,29 +799,75 @@ func createTaskConfig(ctx *context, task config.Task) svcutil.TaskConfig {
 	taskConfig.StartupRandomDelay = time.Duration(task.StartupRandomDelaySecs) * time.Second
 	taskConfig.Logger = ctx.logger
 	taskConfig.ErrorLogger = ctx.errorLogger
+	taskConfig.OutputLogger, taskConfig.OutputErrorLogger = outputLoggers(ctx, task.LogFileConfig)
+	taskConfig.OutputContinuation = continuationPattern(task.OutputContinuationPattern)
+	taskConfig.OnEvent = func(e svcutil.Event) { handleEvent(ctx, e) }
+	taskConfig.Retries = task.Retries
+	taskConfig.RetryDelay = time.Duration(task.RetryDelaySecs) * time.Second
+	taskConfig.MaxRetryDelay = time.Duration(task.RetryMaxDelaySecs) * time.Second
+	taskConfig.SuccessExitCodes = task.SuccessExitCodes
 	return taskConfig
 }
 
+// handleEvent is called when a service or task starts, stops or is pinged,
+// and when the wrapper reloads.
+func handleEvent(ctx *context, e svcutil.Event) {
+	if ctx.metrics != nil {
+		recordEvent(ctx.metrics, e)
+	}
+	if ctx.status != nil {
+		ctx.status.recordEvent(e)
+	}
+	dispatchEvent(ctx, e)
+}
+
+// continuationPattern compiles an output continuation pattern. Patterns are
+// checked when the config is loaded.
+func continuationPattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	return regexp.MustCompile(pattern)
+}
+
 func setupScheduledTasks(ctx *context) {
-	ctx.logger.Printf("Setting up %d scheduled tasks.", len(ctx.conf.ScheduledTasks))
+	logging.Infof(ctx.logger, "Setting up %d scheduled tasks.", len(ctx.conf.ScheduledTasks))
 	ctx.cronManager = cron.New()
+	var tasks []taskStatus
+	var catchUp []*scheduledTaskRunner
+	runs := loadTaskRuns(taskRunsFileName)
+	now := time.Now()
 	for _, scheduledTask := range ctx.conf.ScheduledTasks {
 		taskConfig := createTaskConfig(ctx, scheduledTask.Task)
-		runTask := func() {
-			ctx.runningGroup.Add(1)
-			defer ctx.runningGroup.Done()
-			taskName := path.Base(taskConfig.Path)
-			ctx.logger.Printf("Running schedule task '%s'", taskName)
-			if exitCode, err := svcutil.ExecuteTask(ctx.terminate, taskConfig); err != nil {
-				ctx.errorLogger.Printf("ERROR: Scheduled task '%s' reported: %v", taskName, err)
-			} else {
-				ctx.logger.Printf("The task '%s' finished with exit code %d", taskName, exitCode)
-			}
-		}
-		err := ctx.cronManager.AddFunc(scheduledTask.Schedule, runTask)
+		runner := newScheduledTaskRunner(ctx, scheduledTask.ConcurrencyPolicy, taskConfig)
+		schedule, err := parseSchedule(scheduledTask.Schedule, scheduledTask.Timezone)
 		if err != nil {
-			ctx.errorLogger.Printf("ERROR: Unable to schedule task '%s': %v", scheduledTask.Path, err)
+			logging.Errorf(ctx.errorLogger, "ERROR: Unable to schedule task '%s': %v", scheduledTask.Path, err)
+			continue
 		}
+		if scheduledTask.CatchUp {
+			// The configured path and schedule, so upgrades don't lose track
+			key := scheduledTask.Schedule + " " + scheduledTask.Path
+			runner.onRun = func(t time.Time) {
+				if err := runs.record(key, t); err != nil {
+					logging.Warnf(ctx.logger, "WARNING: Unable to record the run of scheduled task '%s': %v", runner.name, err)
+				}
+			}
+			if lastRun, ok := runs.lastRun(key); !ok {
+				runner.onRun(now)
+			} else if missedRun(schedule, lastRun, now) {
+				logging.Infof(ctx.logger, "Scheduled task '%s' missed a run since %s. Catching up.", runner.name, lastRun.Format(time.RFC3339))
+				catchUp = append(catchUp, runner)
+			}
+		}
+		ctx.cronManager.Schedule(schedule, cron.FuncJob(runner.run))
+		tasks = append(tasks, taskStatus{Name: runner.name, Schedule: scheduledTask.Schedule, schedule: schedule})
+	}
+	if ctx.status != nil {
+		ctx.status.setScheduledTasks(tasks)
 	}
 	ctx.cronManager.Start()
+	for _, runner := range catchUp {
+		go runner.run()
+	}
 }
@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/papercutsoftware/silver/lib/logging"
 	"github.com/papercutsoftware/silver/service/config"
@@ -69,6 +70,57 @@ func TestLogging_SampleConfigWithMicroseconds(t *testing.T) {
 	}
 }
 
+func TestLogging_ServiceLogAgeRetentionKeepsBackups(t *testing.T) {
+	cfg := loadTestConfig(t, "logging-defaultdateformat.conf")
+	dir := t.TempDir()
+	ctx := &context{conf: cfg}
+	logPath := filepath.Join(dir, "service.log")
+
+	// Backups from the last few days, within the service log's retention
+	for i := 1; i <= 3; i++ {
+		day := time.Now().AddDate(0, 0, -i)
+		backup := logPath + "." + day.Format("2006-01-02")
+		if err := os.WriteFile(backup, []byte("old"), 0644); err != nil {
+			t.Fatalf("writing backup: %v", err)
+		}
+		_ = os.Chtimes(backup, day, day)
+	}
+
+	logger, _ := outputLoggers(ctx, config.LogFileConfig{LogFile: logPath, LogFileMaxSizeMb: 1, LogFileMaxAgeDays: 30})
+	t.Cleanup(logging.CloseAllOpenFileLoggers)
+	line := strings.Repeat("x", 600*1024)
+	logger.Print(line)
+	logger.Print(line) // Rolls the log
+	logging.CloseAllOpenFileLoggers()
+
+	backups, _ := filepath.Glob(logPath + ".*")
+	if len(backups) != 4 {
+		t.Fatalf("expected the 3 old backups and the new one to be kept, got %v", backups)
+	}
+}
+
+func TestLogging_CloseOutputLoggersFlushesServiceLog(t *testing.T) {
+	cfg := loadTestConfig(t, "logging-defaultdateformat.conf")
+	ctx := &context{conf: cfg}
+	logPath := filepath.Join(t.TempDir(), "service.log")
+	t.Cleanup(logging.CloseAllOpenFileLoggers)
+
+	logger, _ := outputLoggers(ctx, config.LogFileConfig{LogFile: logPath})
+	logger.Print("before reload")
+	closeOutputLoggers(ctx) // As on reload, once the services have stopped
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("reading log: %v", err)
+	}
+	if !strings.Contains(string(data), "before reload") {
+		t.Errorf("expected the service log to be flushed and closed, got %q", data)
+	}
+	if len(ctx.outputLogs) != 0 {
+		t.Errorf("expected no output loggers left open, got %d", len(ctx.outputLogs))
+	}
+}
+
 func loadTestConfig(t *testing.T, filename string) *config.Config {
 	t.Helper()
 
@@ -0,0 +1,174 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/service/config"
+	"github.com/papercutsoftware/silver/service/svcutil"
+)
+
+func TestWaitForSignal_NewGlobIncludeMatch(t *testing.T) {
+	// Arrange
+	root := t.TempDir()
+	writeFile := func(name string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("v1/include.conf")
+	ctx := &context{conf: &config.Config{}, shutdown: make(chan struct{})}
+	ctx.conf.Include = []string{filepath.Join(root, "v*", "include.conf")}
+	ctx.conf.ServiceConfig.ReloadOnConfigChange = true
+	ctx.conf.ServiceConfig.StopFile = filepath.Join(root, ".stop")
+	ctx.conf.ServiceConfig.ReloadFile = filepath.Join(root, ".reload")
+	defer close(ctx.shutdown)
+	signals := make(chan string, 1)
+	go func() {
+		reason, _ := waitForSignal(ctx)
+		signals <- reason
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// Act - an unrelated file, then a new version's include
+	writeFile("service.log")
+	select {
+	case reason := <-signals:
+		t.Fatalf("Expected no reload for an unrelated file, got %q", reason)
+	case <-time.After(500 * time.Millisecond):
+	}
+	writeFile("v2/include.conf")
+
+	// Assert
+	select {
+	case reason := <-signals:
+		if reason != "Configuration change detected." {
+			t.Errorf("Expected a config change reload, got %q", reason)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("Expected a reload for the new include")
+	}
+}
+
+func TestWatchSignalFiles_ReloadWhileEventsAreRaised(t *testing.T) {
+	// Arrange
+	root := t.TempDir()
+	confPath := getConfigFilePath()
+	if _, err := os.Stat(confPath); err == nil {
+		t.Skipf("%s already exists", confPath)
+	}
+	conf := fmt.Sprintf(`{
+		"ServiceDescription": { "DisplayName": "Reload Test" },
+		"ServiceConfig": { "StopFile": %q, "ReloadFile": %q }
+	}`, filepath.Join(root, ".stop"), filepath.Join(root, ".reload"))
+	if err := os.WriteFile(confPath, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(confPath)
+	loaded, err := loadConf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := &context{
+		conf:        loaded,
+		shutdown:    make(chan struct{}),
+		logger:      logging.NewNilLogger(),
+		errorLogger: logging.NewNilLogger(),
+		status:      &statusTracker{file: filepath.Join(root, "status.json")},
+	}
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		watchSignalFiles(ctx)
+	}()
+	// Events are raised by the service goroutines at any time
+	raising := make(chan struct{})
+	stopRaising := make(chan struct{})
+	go func() {
+		defer close(raising)
+		for !svcutil.IsClosed(stopRaising) {
+			handleEvent(ctx, svcutil.Event{Type: svcutil.EventServiceStarted, Time: time.Now(), Name: "my-app"})
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// Act
+	if err := os.WriteFile(loaded.ServiceConfig.ReloadFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	deadline := time.Now().Add(15 * time.Second)
+	for ctx.currentConf() == loaded && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	close(stopRaising)
+	<-raising
+	close(ctx.shutdown)
+	<-watching
+	ctx.lifecycle.Lock()
+	doStop(ctx)
+	ctx.lifecycle.Unlock()
+	if ctx.currentConf() == loaded {
+		t.Fatal("Expected the config to be reloaded")
+	}
+}
+
+func TestWatchSignalFiles_StopFileShutsDownWrapper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shutdown is requested with Control-Break on Windows, which can't be caught by the test")
+	}
+	// Arrange
+	root := t.TempDir()
+	ctx := &context{conf: &config.Config{}, shutdown: make(chan struct{}), logger: logging.NewNilLogger(), errorLogger: logging.NewNilLogger()}
+	ctx.conf.ServiceConfig.StopFile = filepath.Join(root, ".stop")
+	ctx.conf.ServiceConfig.ReloadFile = filepath.Join(root, ".reload")
+	defer close(ctx.shutdown)
+	// The same signals the service runner waits on to stop the wrapper
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		watchSignalFiles(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// Act
+	if err := os.WriteFile(ctx.conf.ServiceConfig.StopFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	select {
+	case <-signals:
+	case <-time.After(15 * time.Second):
+		t.Fatal("Expected the stop file to request a shutdown")
+	}
+	select {
+	case <-watching:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the watcher to stop once shutdown was requested")
+	}
+}
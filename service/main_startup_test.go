@@ -0,0 +1,55 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/papercutsoftware/silver/service/config"
+)
+
+func TestStartupTasks_OnFailure(t *testing.T) {
+	failing := config.Task{}
+	failing.Path = "does-not-exist"
+	succeeding := config.Task{}
+	succeeding.Path = os.Args[0] // This test binary, running no tests
+	succeeding.Args = []string{"-test.run=^$"}
+
+	for _, test := range []struct {
+		onFailure   string
+		failureTask *config.Task
+		started     bool
+	}{
+		{onFailure: "", started: true},
+		{onFailure: "continue", started: true},
+		{onFailure: "abort", started: false},
+		{onFailure: "task", failureTask: &succeeding, started: true},
+		{onFailure: "task", failureTask: &failing, started: false},
+	} {
+		// Arrange
+		ctx := &context{
+			conf: &config.Config{StartupTasks: []config.StartupTask{
+				{Task: failing, OnFailure: test.onFailure, FailureTask: test.failureTask},
+			}},
+			terminate:   make(chan struct{}),
+			logger:      log.New(io.Discard, "", 0),
+			errorLogger: log.New(io.Discard, "", 0),
+		}
+
+		// Act
+		started := execStartupTasks(ctx)
+
+		// Assert
+		if started != test.started {
+			t.Errorf("OnFailure %q: expected services started %v, got %v", test.onFailure, test.started, started)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/lib/metrics"
+	"github.com/papercutsoftware/silver/lib/osutils"
+	"github.com/papercutsoftware/silver/service/svcutil"
+	"github.com/papercutsoftware/silver/updater/update"
+)
+
+// newMetrics sets up the registry. Metrics are kept for the life of the
+// wrapper, across reloads.
+func newMetrics() *metrics.Registry {
+	r := metrics.NewRegistry()
+	r.Describe("silver_service_up", metrics.TypeGauge, "Whether the service process is running.")
+	r.Describe("silver_service_restarts_total", metrics.TypeCounter, "Service restarts, after a crash, a failed monitor or when requested.")
+	r.Describe("silver_service_crashes_total", metrics.TypeCounter, "Times the service stopped unexpectedly.")
+	r.Describe("silver_service_crash_limit_exceeded_total", metrics.TypeCounter, "Times the service was given up on after too many crashes.")
+	r.Describe("silver_monitor_ping_duration_seconds", metrics.TypeSummary, "Monitor ping latency.")
+	r.Describe("silver_monitor_ping_failures_total", metrics.TypeCounter, "Failed monitor pings.")
+	r.Describe("silver_monitor_flapping_total", metrics.TypeCounter, "Times the monitors restarted the service too often.")
+	r.Describe("silver_task_runs_total", metrics.TypeCounter, "Startup and scheduled task runs.")
+	r.Describe("silver_task_failures_total", metrics.TypeCounter, "Task runs that returned an error or non-zero exit code.")
+	r.Describe("silver_task_duration_seconds", metrics.TypeSummary, "Task run time.")
+	r.Describe("silver_task_skipped_total", metrics.TypeCounter, "Scheduled task runs skipped while the previous run was still going.")
+	r.Describe("silver_update_last_check_timestamp_seconds", metrics.TypeGauge, "Time of the last update check.")
+	r.Describe("silver_update_last_check_success", metrics.TypeGauge, "Whether the last update check succeeded.")
+	r.Describe("silver_update_last_check_updated", metrics.TypeGauge, "Whether the last update check installed an update.")
+	r.Describe("silver_log_bytes_written_total", metrics.TypeCounter, "Bytes written to each log file.")
+	r.AddCollector(collectUpdateResult)
+	r.AddCollector(collectLogBytes)
+	return r
+}
+
+// recordEvent updates the metrics for a service or task event.
+func recordEvent(r *metrics.Registry, e svcutil.Event) {
+	svc := metrics.Labels{"service": e.Name}
+	switch e.Type {
+	case svcutil.EventServiceStarted:
+		r.Set("silver_service_up", svc, 1)
+	case svcutil.EventServiceStopped:
+		r.Set("silver_service_up", svc, 0)
+	case svcutil.EventServiceCrashed:
+		r.Inc("silver_service_crashes_total", svc)
+	case svcutil.EventServiceRestarted:
+		reason := "requested"
+		switch e.Reason {
+		case svcutil.RestartReasonCrash:
+			reason = "crash"
+		case svcutil.RestartReasonMonitor:
+			reason = "monitor"
+		}
+		r.Inc("silver_service_restarts_total", metrics.Labels{"service": e.Name, "reason": reason})
+	case svcutil.EventCrashLimitExceeded:
+		r.Inc("silver_service_crash_limit_exceeded_total", svc)
+	case svcutil.EventMonitorFlapping:
+		r.Inc("silver_monitor_flapping_total", svc)
+	case svcutil.EventMonitorOK, svcutil.EventMonitorFailed:
+		ping := metrics.Labels{"service": e.Name, "url": e.URL}
+		r.Observe("silver_monitor_ping_duration_seconds", ping, e.Duration.Seconds())
+		if e.Type == svcutil.EventMonitorFailed {
+			r.Inc("silver_monitor_ping_failures_total", ping)
+		} else {
+			// Make sure the series exists before the first failure
+			r.Add("silver_monitor_ping_failures_total", ping, 0)
+		}
+	case svcutil.EventTaskFinished, svcutil.EventTaskFailed:
+		task := metrics.Labels{"task": e.Name}
+		r.Inc("silver_task_runs_total", task)
+		r.Observe("silver_task_duration_seconds", task, e.Duration.Seconds())
+		if e.Type == svcutil.EventTaskFailed {
+			r.Inc("silver_task_failures_total", task)
+		} else {
+			r.Add("silver_task_failures_total", task, 0)
+		}
+	case svcutil.EventTaskSkipped:
+		r.Inc("silver_task_skipped_total", metrics.Labels{"task": e.Name})
+	}
+}
+
+// collectUpdateResult reports the last update check recorded by the updater.
+func collectUpdateResult(r *metrics.Registry) {
+	result := update.ReadResult(update.ResultFileName)
+	if result == nil {
+		return
+	}
+	r.Set("silver_update_last_check_timestamp_seconds", nil, float64(result.Time.Unix()))
+	r.Set("silver_update_last_check_success", nil, boolValue(result.Result != update.ResultFailed))
+	r.Set("silver_update_last_check_updated", nil, boolValue(result.Result == update.ResultUpdated))
+}
+
+func collectLogBytes(r *metrics.Registry) {
+	for file, n := range logging.BytesWritten() {
+		r.Set("silver_log_bytes_written_total", metrics.Labels{"file": file}, float64(n))
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// startMetrics serves the metrics on the configured address, restarting the
+// server if the address changed on reload.
+func startMetrics(ctx *context) {
+	addr := ctx.currentConf().ServiceConfig.MetricsAddress
+	if ctx.metricsServer != nil && ctx.metricsServer.Addr == addr {
+		return
+	}
+	stopMetrics(ctx)
+	if addr == "" {
+		return
+	}
+	if host, _, _ := net.SplitHostPort(addr); !osutils.IsLocalhost(host) {
+		logging.Warnf(ctx.logger, "WARNING: Metrics are served on a non-local address %s", addr)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logging.Errorf(ctx.errorLogger, "ERROR: Unable to serve metrics on %s: %v", addr, err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", ctx.metrics)
+	ctx.metricsServer = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func(server *http.Server) {
+		_ = server.Serve(listener)
+	}(ctx.metricsServer)
+	logging.Infof(ctx.logger, "Serving metrics on http://%s/metrics", listener.Addr())
+}
+
+func stopMetrics(ctx *context) {
+	if ctx.metricsServer != nil {
+		_ = ctx.metricsServer.Close()
+		ctx.metricsServer = nil
+	}
+}
@@ -0,0 +1,210 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/service/svcutil"
+	"github.com/robfig/cron"
+)
+
+// Records when CatchUp tasks last ran, in the service root
+const taskRunsFileName = ".task-runs.json"
+
+// Scheduled task concurrency policies: what's done when a task is due while
+// its previous run is still going
+const (
+	concurrencyAllow   = "allow"   // Run another copy (default)
+	concurrencySkip    = "skip"    // Skip this run
+	concurrencyQueue   = "queue"   // Run once the previous run finishes. Only one run waits
+	concurrencyReplace = "replace" // Stop the previous run, then run
+)
+
+// scheduledTaskRunner runs a scheduled task on each tick according to its
+// concurrency policy.
+type scheduledTaskRunner struct {
+	ctx    *context
+	name   string
+	policy string
+	// execute runs the task until it finishes or terminate is closed
+	execute func(terminate chan struct{}) (exitCode int, err error)
+	// onRun, if set, is called on each tick e.g. to record the run
+	onRun func(time.Time)
+
+	lock    sync.Mutex
+	running int
+	pending bool          // Another run is due once the current one finishes
+	stop    chan struct{} // Closed to stop the current run
+}
+
+func newScheduledTaskRunner(ctx *context, policy string, taskConfig svcutil.TaskConfig) *scheduledTaskRunner {
+	if policy == "" {
+		policy = concurrencyAllow
+	}
+	return &scheduledTaskRunner{
+		ctx:    ctx,
+		name:   filepath.Base(taskConfig.Path),
+		policy: policy,
+		execute: func(terminate chan struct{}) (int, error) {
+			return svcutil.ExecuteTask(terminate, taskConfig)
+		},
+	}
+}
+
+// run is called on each tick of the task's schedule.
+func (r *scheduledTaskRunner) run() {
+	if r.onRun != nil {
+		r.onRun(time.Now())
+	}
+	r.lock.Lock()
+	if r.running > 0 && r.policy != concurrencyAllow {
+		switch r.policy {
+		case concurrencySkip:
+			r.lock.Unlock()
+			r.skipped("the previous run is still going")
+			return
+		case concurrencyQueue:
+			if r.pending {
+				r.lock.Unlock()
+				r.skipped("a run is already queued")
+				return
+			}
+			logging.Infof(r.ctx.logger, "Scheduled task '%s' queued until the previous run finishes", r.name)
+		case concurrencyReplace:
+			if !svcutil.IsClosed(r.stop) {
+				logging.Infof(r.ctx.logger, "Stopping the previous run of scheduled task '%s' to replace it", r.name)
+				close(r.stop)
+			}
+		}
+		r.pending = true
+		r.lock.Unlock()
+		return
+	}
+	r.running++
+	stop := make(chan struct{})
+	r.stop = stop
+	r.lock.Unlock()
+
+	r.ctx.runningGroup.Add(1)
+	defer r.ctx.runningGroup.Done()
+	for {
+		r.runOnce(stop)
+
+		// Start any run that was due while this one was going
+		r.lock.Lock()
+		if !r.pending || svcutil.IsClosed(r.ctx.terminate) {
+			r.running--
+			r.pending = false
+			r.lock.Unlock()
+			return
+		}
+		r.pending = false
+		stop = make(chan struct{})
+		r.stop = stop
+		r.lock.Unlock()
+	}
+}
+
+func (r *scheduledTaskRunner) runOnce(stop chan struct{}) {
+	terminate := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-r.ctx.terminate:
+		case <-stop:
+		case <-done:
+			return
+		}
+		close(terminate)
+	}()
+	logging.Debugf(r.ctx.logger, "Running schedule task '%s'", r.name)
+	if exitCode, err := r.execute(terminate); err != nil {
+		logging.Errorf(r.ctx.errorLogger, "ERROR: Scheduled task '%s' reported: %v", r.name, err)
+	} else {
+		logging.Infof(r.ctx.logger, "The task '%s' finished with exit code %d", r.name, exitCode)
+	}
+}
+
+func (r *scheduledTaskRunner) skipped(reason string) {
+	logging.Warnf(r.ctx.logger, "WARNING: Skipped scheduled task '%s', %s", r.name, reason)
+	handleEvent(r.ctx, svcutil.Event{Type: svcutil.EventTaskSkipped, Time: time.Now(), Name: r.name, Reason: reason})
+}
+
+// zonedSchedule runs a schedule in a time zone other than the wrapper's.
+type zonedSchedule struct {
+	cron.Schedule
+	location *time.Location
+}
+
+func (s zonedSchedule) Next(t time.Time) time.Time {
+	return s.Schedule.Next(t.In(s.location))
+}
+
+// parseSchedule parses the task's cron schedule in its time zone.
+func parseSchedule(spec, timezone string) (cron.Schedule, error) {
+	schedule, err := cron.Parse(spec)
+	if err != nil || timezone == "" {
+		return schedule, err
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	return zonedSchedule{Schedule: schedule, location: location}, nil
+}
+
+// taskRuns is when tasks last ran, persisted so runs missed while the
+// service was stopped can be caught up.
+type taskRuns struct {
+	sync.Mutex
+	file string
+	last map[string]time.Time // By task key
+}
+
+func loadTaskRuns(file string) *taskRuns {
+	tr := &taskRuns{file: file, last: make(map[string]time.Time)}
+	if b, err := os.ReadFile(file); err == nil {
+		_ = json.Unmarshal(b, &tr.last)
+	}
+	return tr
+}
+
+func (tr *taskRuns) lastRun(key string) (time.Time, bool) {
+	tr.Lock()
+	defer tr.Unlock()
+	t, ok := tr.last[key]
+	return t, ok
+}
+
+func (tr *taskRuns) record(key string, t time.Time) error {
+	tr.Lock()
+	defer tr.Unlock()
+	tr.last[key] = t
+	b, err := json.MarshalIndent(tr.last, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := tr.file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, tr.file)
+}
+
+// missedRun returns true if the schedule was due between the last run and now.
+func missedRun(schedule cron.Schedule, lastRun, now time.Time) bool {
+	next := schedule.Next(lastRun)
+	return !next.IsZero() && !next.After(now)
+}
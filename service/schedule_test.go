@@ -0,0 +1,151 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/papercutsoftware/silver/service/config"
+)
+
+func TestScheduledTaskRunner_ConcurrencyPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy       string
+		started      []int // Runs started after each of three ticks, while the runs are held
+		runs         int32 // Runs started in all, once released
+		terminated   int32 // Runs stopped early
+		skippedTotal string
+	}{
+		{policy: concurrencyAllow, started: []int{1, 2, 3}, runs: 3},
+		{policy: concurrencySkip, started: []int{1, 1, 1}, runs: 1, skippedTotal: `silver_task_skipped_total{task="cleanup.exe"} 2`},
+		{policy: concurrencyQueue, started: []int{1, 1, 1}, runs: 2, skippedTotal: `silver_task_skipped_total{task="cleanup.exe"} 1`},
+		{policy: concurrencyReplace, started: []int{1, 2, 3}, runs: 3, terminated: 2},
+	} {
+		// Arrange
+		ctx := &context{
+			conf:        &config.Config{},
+			terminate:   make(chan struct{}),
+			logger:      log.New(io.Discard, "", 0),
+			errorLogger: log.New(io.Discard, "", 0),
+			metrics:     newMetrics(),
+		}
+		var runs, terminated atomic.Int32
+		started := make(chan struct{}, 10)
+		release := make(chan struct{})
+		r := &scheduledTaskRunner{ctx: ctx, name: "cleanup.exe", policy: test.policy}
+		r.execute = func(terminate chan struct{}) (int, error) {
+			runs.Add(1)
+			started <- struct{}{}
+			select {
+			case <-release:
+				return 0, nil
+			case <-terminate:
+				terminated.Add(1)
+				return 1, errors.New("terminated")
+			}
+		}
+		waitFor := func(c chan struct{}, what string) {
+			select {
+			case <-c:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: timed out waiting for %s", test.policy, what)
+			}
+		}
+		var calls sync.WaitGroup
+		tick := func() chan struct{} {
+			returned := make(chan struct{})
+			calls.Add(1)
+			go func() {
+				defer calls.Done()
+				defer close(returned)
+				r.run()
+			}()
+			return returned
+		}
+
+		// Act: each tick is handled before the next, while the runs are held
+		count := 0
+		for _, expected := range test.started {
+			returned := tick()
+			if expected == count {
+				// Skipped or queued
+				waitFor(returned, "the tick to return")
+			}
+			for ; count < expected; count++ {
+				waitFor(started, "a run to start")
+			}
+		}
+		close(release)
+		for ; count < int(test.runs); count++ {
+			waitFor(started, "a queued run to start")
+		}
+		calls.Wait()
+		ctx.runningGroup.Wait()
+
+		// Assert
+		if runs.Load() != test.runs || terminated.Load() != test.terminated {
+			t.Errorf("%s: expected %d runs (%d terminated), got %d (%d terminated)",
+				test.policy, test.runs, test.terminated, runs.Load(), terminated.Load())
+		}
+		var metrics bytes.Buffer
+		ctx.metrics.WriteText(&metrics)
+		if test.skippedTotal != "" && !strings.Contains(metrics.String(), test.skippedTotal) {
+			t.Errorf("%s: expected %s, got:\n%s", test.policy, test.skippedTotal, metrics.String())
+		}
+	}
+}
+
+func TestParseSchedule_Timezone(t *testing.T) {
+	// Arrange
+	schedule, err := parseSchedule("0 0 9 * * *", "Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Act
+	next := schedule.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// Assert: 9am in Tokyo is midnight UTC
+	if want := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("Expected next run %v, got %v", want, next)
+	}
+}
+
+func TestTaskRuns_MissedRun(t *testing.T) {
+	// Arrange
+	file := filepath.Join(t.TempDir(), taskRunsFileName)
+	schedule, _ := parseSchedule("@daily", "")
+	lastRun := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	if err := loadTaskRuns(file).record("@daily task", lastRun); err != nil {
+		t.Fatalf("Unable to record run: %v", err)
+	}
+
+	// Act: reloaded, as at the next start
+	runs := loadTaskRuns(file)
+	recorded, ok := runs.lastRun("@daily task")
+
+	// Assert
+	if !ok || !recorded.Equal(lastRun) {
+		t.Fatalf("Expected last run %v, got %v (%v)", lastRun, recorded, ok)
+	}
+	if missedRun(schedule, recorded, lastRun.Add(23*time.Hour)) {
+		t.Errorf("Expected no missed run within the day")
+	}
+	if !missedRun(schedule, recorded, lastRun.Add(25*time.Hour)) {
+		t.Errorf("Expected a missed run after a day")
+	}
+}
@@ -0,0 +1,315 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kardianos/service"
+	"github.com/papercutsoftware/silver/service/svcutil"
+	"github.com/papercutsoftware/silver/updater/update"
+	"github.com/robfig/cron"
+)
+
+const (
+	statusTimeFormat    = "2006-01-02 15:04:05"
+	statusWriteInterval = 5 * time.Second
+	// A status file not updated for this long was left by a wrapper that crashed or was killed
+	statusStaleAfter = 3 * statusWriteInterval
+)
+
+// Service states
+const (
+	serviceStarting   = "starting"
+	serviceRunning    = "running"
+	serviceRestarting = "restarting"
+	serviceStopped    = "stopped"
+	serviceFailed     = "failed" // Crash limit exceeded, or stopped for flapping
+)
+
+// wrapperStatus is the running wrapper's state, written to the status file so
+// that it can be read by the status command (and other tools).
+type wrapperStatus struct {
+	Updated        time.Time
+	PID            int
+	Version        string `json:",omitempty"` // Installed version
+	Services       []serviceStatus
+	ScheduledTasks []taskStatus
+	Update         *update.Result `json:",omitempty"` // Last update check
+	Reload         *reloadStatus  `json:",omitempty"`
+}
+
+type serviceStatus struct {
+	Name         string
+	State        string
+	PID          int       `json:",omitempty"`
+	Since        time.Time // When the service entered its current state
+	Restarts     int
+	Crashes      int
+	LastExitCode int
+	LastError    string `json:",omitempty"`
+}
+
+type taskStatus struct {
+	Name         string
+	Schedule     string
+	NextRun      time.Time
+	LastRun      *time.Time `json:",omitempty"`
+	LastExitCode int
+	LastError    string `json:",omitempty"`
+	Skipped      int    // Runs skipped by the ConcurrencyPolicy
+
+	schedule cron.Schedule
+}
+
+type reloadStatus struct {
+	Time     time.Time
+	Rejected bool
+	Error    string `json:",omitempty"`
+}
+
+type statusTracker struct {
+	sync.Mutex
+	file    string
+	status  wrapperStatus
+	removed bool // Once stopped the file isn't written again
+}
+
+// run writes the status every few seconds until shutdown.
+func (st *statusTracker) run(shutdown chan struct{}) {
+	ticker := time.NewTicker(statusWriteInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			st.write()
+		case <-shutdown:
+			return
+		}
+	}
+}
+
+func (st *statusTracker) setVersion(version string) {
+	st.Lock()
+	defer st.Unlock()
+	st.status.Version = version
+}
+
+// setServices resets the state of the services when they're (re)started.
+func (st *statusTracker) setServices(names []string) {
+	st.Lock()
+	defer st.Unlock()
+	st.status.Services = make([]serviceStatus, 0, len(names))
+	for _, name := range names {
+		st.status.Services = append(st.status.Services, serviceStatus{Name: name, State: serviceStarting, Since: time.Now()})
+	}
+}
+
+// setScheduledTasks resets the scheduled tasks. Tasks with invalid schedules
+// aren't run, so aren't listed.
+func (st *statusTracker) setScheduledTasks(tasks []taskStatus) {
+	st.Lock()
+	defer st.Unlock()
+	st.status.ScheduledTasks = tasks
+}
+
+// recordEvent updates the service or task the event is for.
+func (st *statusTracker) recordEvent(e svcutil.Event) {
+	st.Lock()
+	defer st.Unlock()
+	switch e.Type {
+	case svcutil.EventTaskFinished, svcutil.EventTaskFailed:
+		for i := range st.status.ScheduledTasks {
+			if t := &st.status.ScheduledTasks[i]; t.Name == e.Name {
+				start := e.Time.Add(-e.Duration)
+				t.LastRun = &start
+				t.LastExitCode = e.ExitCode
+				t.LastError = e.Error
+			}
+		}
+		return
+	case svcutil.EventTaskSkipped:
+		for i := range st.status.ScheduledTasks {
+			if t := &st.status.ScheduledTasks[i]; t.Name == e.Name {
+				t.Skipped++
+			}
+		}
+		return
+	}
+	for i := range st.status.Services {
+		s := &st.status.Services[i]
+		if s.Name != e.Name {
+			continue
+		}
+		switch e.Type {
+		case svcutil.EventServiceStarted:
+			s.State, s.PID, s.Since = serviceRunning, e.PID, e.Time
+		case svcutil.EventServiceStopped:
+			s.State, s.PID, s.Since = serviceStopped, 0, e.Time
+			s.LastExitCode = e.ExitCode
+			s.LastError = e.Error
+		case svcutil.EventServiceCrashed:
+			s.Crashes++
+		case svcutil.EventServiceRestarted:
+			s.State, s.Since = serviceRestarting, e.Time
+			s.Restarts++
+		case svcutil.EventCrashLimitExceeded:
+			s.State, s.Since = serviceFailed, e.Time
+			s.LastError = e.Error
+		case svcutil.EventMonitorFlapping:
+			if e.Reason == svcutil.FlapActionStop {
+				s.State, s.Since = serviceFailed, e.Time
+			}
+			s.LastError = e.Error
+		}
+	}
+}
+
+func statusFileName(ctx *context) string {
+	f := ctx.currentConf().ServiceConfig.StatusFile
+	if f == "" {
+		f = serviceName() + ".status.json"
+	}
+	return f
+}
+
+func (st *statusTracker) setReload(err error) {
+	st.Lock()
+	st.status.Reload = &reloadStatus{Time: time.Now()}
+	if err != nil {
+		st.status.Reload.Rejected = true
+		st.status.Reload.Error = err.Error()
+	}
+	st.Unlock()
+	st.write()
+}
+
+// write atomically replaces the status file so readers never see a partial file.
+func (st *statusTracker) write() {
+	st.Lock()
+	defer st.Unlock()
+	if st.removed {
+		return
+	}
+	st.status.Updated = time.Now()
+	st.status.PID = os.Getpid()
+	st.status.Update = update.ReadResult(update.ResultFileName)
+	for i := range st.status.ScheduledTasks {
+		t := &st.status.ScheduledTasks[i]
+		t.NextRun = t.schedule.Next(st.status.Updated)
+	}
+	b, err := json.MarshalIndent(st.status, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := st.file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, st.file)
+}
+
+func (st *statusTracker) remove() {
+	st.Lock()
+	defer st.Unlock()
+	st.removed = true
+	_ = os.Remove(st.file)
+}
+
+func readStatus(file string) (*wrapperStatus, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	status := &wrapperStatus{}
+	if err := json.Unmarshal(b, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// stale returns true if the wrapper that wrote the status is no longer running.
+func (s *wrapperStatus) stale(now time.Time) bool {
+	return now.Sub(s.Updated) > statusStaleAfter
+}
+
+func printStatus(ctx *context) int {
+	svc, err := newOSService(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: Invalid service config: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Service:     %s\n", serviceName())
+	fmt.Printf("OS status:   %s\n", osStatusText(svc))
+
+	status, err := readStatus(statusFileName(ctx))
+	if err != nil {
+		fmt.Printf("Wrapper:     not running\n")
+		return 0
+	}
+	if status.stale(time.Now()) {
+		fmt.Printf("Wrapper:     not running (stale status from %s, PID %d)\n", status.Updated.Format(statusTimeFormat), status.PID)
+		return 0
+	}
+	fmt.Printf("Wrapper PID: %d (updated %s)\n", status.PID, status.Updated.Format(statusTimeFormat))
+	if status.Version != "" {
+		fmt.Printf("Version:     %s\n", status.Version)
+	}
+	if u := status.Update; u != nil {
+		fmt.Printf("Last update: %s at %s", u.Result, u.Time.Format(statusTimeFormat))
+		if u.Error != "" {
+			fmt.Printf(" - %s", u.Error)
+		}
+		fmt.Println()
+	}
+	for _, s := range status.Services {
+		fmt.Printf("Service:     %s %s since %s", s.Name, s.State, s.Since.Format(statusTimeFormat))
+		if s.PID != 0 {
+			fmt.Printf(" (PID %d)", s.PID)
+		}
+		fmt.Printf(", restarts %d, crashes %d\n", s.Restarts, s.Crashes)
+	}
+	for _, t := range status.ScheduledTasks {
+		fmt.Printf("Task:        %s next run %s", t.Name, t.NextRun.Format(statusTimeFormat))
+		if t.LastRun != nil {
+			fmt.Printf(", last run %s exit code %d", t.LastRun.Format(statusTimeFormat), t.LastExitCode)
+		}
+		if t.Skipped > 0 {
+			fmt.Printf(", skipped %d", t.Skipped)
+		}
+		fmt.Println()
+	}
+	if r := status.Reload; r != nil {
+		if r.Rejected {
+			fmt.Printf("Last reload: REJECTED at %s - %s\n", r.Time.Format(statusTimeFormat), r.Error)
+			fmt.Printf("             Still running the previous config.\n")
+		} else {
+			fmt.Printf("Last reload: OK at %s\n", r.Time.Format(statusTimeFormat))
+		}
+	}
+	return 0
+}
+
+func osStatusText(svc service.Service) string {
+	status, err := svc.Status()
+	if err != nil {
+		return fmt.Sprintf("unknown (%v)", err)
+	}
+	switch status {
+	case service.StatusRunning:
+		return "running"
+	case service.StatusStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,111 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/papercutsoftware/silver/service/svcutil"
+	"github.com/robfig/cron"
+)
+
+func TestStatus_RejectedReloadRoundTrip(t *testing.T) {
+	// Arrange
+	st := &statusTracker{file: filepath.Join(t.TempDir(), "silver-test.status.json")}
+
+	// Act
+	st.setReload(errors.New("include v2/component.conf: unexpected end of JSON input"))
+
+	// Assert
+	status, err := readStatus(st.file)
+	if err != nil {
+		t.Fatalf("reading status: %v", err)
+	}
+	if status.Reload == nil || !status.Reload.Rejected {
+		t.Fatalf("expected a rejected reload, got %+v", status.Reload)
+	}
+	if status.Reload.Error != "include v2/component.conf: unexpected end of JSON input" {
+		t.Errorf("expected the exact reload error, got %q", status.Reload.Error)
+	}
+	if status.PID == 0 {
+		t.Errorf("expected the wrapper PID")
+	}
+
+	// Act - a later successful reload
+	st.setReload(nil)
+
+	// Assert
+	status, err = readStatus(st.file)
+	if err != nil {
+		t.Fatalf("reading status: %v", err)
+	}
+	if status.Reload.Rejected || status.Reload.Error != "" {
+		t.Errorf("expected a successful reload, got %+v", status.Reload)
+	}
+}
+
+func TestStatus_ServiceAndTaskState(t *testing.T) {
+	// Arrange
+	st := &statusTracker{file: filepath.Join(t.TempDir(), "silver-test.status.json")}
+	schedule, err := cron.Parse("0 30 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.setVersion("2.1")
+	st.setServices([]string{"my-app"})
+	st.setScheduledTasks([]taskStatus{{Name: "cleanup", Schedule: "0 30 * * * *", schedule: schedule}})
+
+	now := time.Now()
+
+	// Act
+	st.recordEvent(svcutil.Event{Type: svcutil.EventServiceStarted, Time: now, Name: "my-app", PID: 123})
+	st.recordEvent(svcutil.Event{Type: svcutil.EventServiceStopped, Time: now, Name: "my-app", PID: 123, ExitCode: 2})
+	st.recordEvent(svcutil.Event{Type: svcutil.EventServiceCrashed, Time: now, Name: "my-app", ExitCode: 2})
+	st.recordEvent(svcutil.Event{Type: svcutil.EventServiceRestarted, Time: now, Name: "my-app", Reason: "crash"})
+	st.recordEvent(svcutil.Event{Type: svcutil.EventServiceStarted, Time: now, Name: "my-app", PID: 456})
+	st.recordEvent(svcutil.Event{Type: svcutil.EventTaskFailed, Time: now, Name: "cleanup", ExitCode: 1, Duration: time.Second})
+	st.write()
+
+	// Assert
+	status, err := readStatus(st.file)
+	if err != nil {
+		t.Fatalf("reading status: %v", err)
+	}
+	if status.Version != "2.1" {
+		t.Errorf("expected the installed version, got %q", status.Version)
+	}
+	s := status.Services[0]
+	if s.State != serviceRunning || s.PID != 456 || s.Restarts != 1 || s.Crashes != 1 || s.LastExitCode != 2 {
+		t.Errorf("unexpected service status %+v", s)
+	}
+	task := status.ScheduledTasks[0]
+	if task.LastRun == nil || task.LastExitCode != 1 {
+		t.Errorf("expected the last run, got %+v", task)
+	}
+	if task.NextRun.Minute() != 30 || !task.NextRun.After(now) {
+		t.Errorf("expected the next run at half past, got %s", task.NextRun)
+	}
+}
+
+func TestStatus_StaleAfterWrapperStops(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	current := &wrapperStatus{Updated: now.Add(-statusWriteInterval)}
+	leftover := &wrapperStatus{Updated: now.Add(-time.Hour)}
+
+	// Act & Assert
+	if current.stale(now) {
+		t.Errorf("expected a recently written status to be current")
+	}
+	if !leftover.stale(now) {
+		t.Errorf("expected a status not written for an hour to be stale")
+	}
+}
@@ -0,0 +1,268 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package svcutil
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/lib/osutils"
+)
+
+// Environment variables with names like these have their values removed
+var sensitiveEnvName = regexp.MustCompile(`(?i)pass|secret|token|key|credential|auth`)
+
+// Bundles are written one at a time
+var crashBundleLock sync.Mutex
+
+// CrashBundleConfig is the crash bundle written when a service stops
+// unexpectedly, for support staff to attach to tickets.
+type CrashBundleConfig struct {
+	Dir      string        // Where bundles are written. Empty to disable
+	Files    []string      // Globs of extra files (e.g. core, hs_err_pid*.log) included if written during the run
+	MaxCount int           // Bundles kept for the service (0 = no limit)
+	MaxAge   time.Duration // Bundles older than this are removed (0 = no limit)
+	// Larger Files (e.g. cores) are listed in the report rather than copied (0 = no limit)
+	MaxFileSize int64
+	Version     string // Installed version of the application
+}
+
+// crashReport is the summary of the crash, crash.json in the bundle.
+type crashReport struct {
+	Service     string
+	Time        time.Time
+	ExitCode    int
+	ExitStatus  string `json:",omitempty"` // e.g. "signal: segmentation fault (core dumped)"
+	Error       string `json:",omitempty"`
+	PID         int    `json:",omitempty"`
+	Uptime      string
+	Path        string
+	Args        []string
+	Binary      *binaryInfo `json:",omitempty"`
+	Version     string      `json:",omitempty"`
+	Environment []string
+	Files       []string `json:",omitempty"`
+	LargeFiles  []string `json:",omitempty"` // Paths of files over the size limit, not included
+}
+
+type binaryInfo struct {
+	Size    int64
+	ModTime time.Time
+	SHA256  string
+}
+
+type crashDetails struct {
+	exitCode  int
+	exitState *os.ProcessState
+	err       error
+	pid       int
+	started   time.Time
+	env       []string
+	output    []logging.Record
+}
+
+// writeCrashBundle writes a zip of the crash report, the last lines of
+// output and any matching crash files, then removes old bundles.
+func (che *crashHandlingExecutable) writeCrashBundle(details crashDetails) (string, error) {
+	conf := che.svcConfig.CrashConfig.Bundle
+	crashBundleLock.Lock()
+	defer crashBundleLock.Unlock()
+
+	now := time.Now()
+	report := crashReport{
+		Service:     che.serviceName,
+		Time:        now,
+		ExitCode:    details.exitCode,
+		PID:         details.pid,
+		Uptime:      now.Sub(details.started).Round(time.Millisecond).String(),
+		Path:        che.svcConfig.Path,
+		Args:        che.svcConfig.Args,
+		Binary:      describeBinary(che.svcConfig.Path),
+		Version:     conf.Version,
+		Environment: redactEnv(details.env),
+	}
+	if details.exitState != nil {
+		report.ExitStatus = details.exitState.String()
+	}
+	if details.err != nil {
+		report.Error = details.err.Error()
+	}
+	var files []string
+	for _, f := range crashFiles(conf.Files, details.started) {
+		if info, err := os.Stat(f); err == nil && conf.MaxFileSize > 0 && info.Size() > conf.MaxFileSize {
+			abs, _ := filepath.Abs(f)
+			report.LargeFiles = append(report.LargeFiles, fmt.Sprintf("%s (%d bytes)", abs, info.Size()))
+			continue
+		}
+		files = append(files, f)
+		report.Files = append(report.Files, filepath.Base(f))
+	}
+
+	if err := os.MkdirAll(conf.Dir, 0o755); err != nil {
+		return "", err
+	}
+	prefix := strings.TrimSuffix(che.serviceName, ".exe")
+	base := filepath.Join(conf.Dir, fmt.Sprintf("%s-%s", prefix, now.Format("20060102-150405")))
+	name := base + ".zip"
+	for i := 1; osutils.FileExists(name); i++ {
+		name = fmt.Sprintf("%s-%d.zip", base, i)
+	}
+	tmp := name + ".tmp"
+	if err := writeZip(tmp, report, details.output, files); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	pruneCrashBundles(conf, prefix)
+	return name, nil
+}
+
+func writeZip(name string, report crashReport, output []logging.Record, files []string) (err error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	zw := zip.NewWriter(f)
+
+	w, err := zw.Create("crash.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	w, err = zw.Create("output.log")
+	if err != nil {
+		return err
+	}
+	for _, r := range output {
+		fmt.Fprintf(w, "%s %s|%s\n", r.Time.Format("2006-01-02 15:04:05.000"), strings.ToUpper(r.Stream), r.Message)
+	}
+
+	for _, file := range files {
+		if err := addZipFile(zw, file, "files/"+filepath.Base(file)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func addZipFile(zw *zip.Writer, file, name string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
+
+func describeBinary(path string) *binaryInfo {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil
+	}
+	return &binaryInfo{Size: info.Size(), ModTime: info.ModTime(), SHA256: hex.EncodeToString(h.Sum(nil))}
+}
+
+// redactEnv removes the values of sensitive looking variables, and applies
+// the log redaction rules to the rest.
+func redactEnv(env []string) []string {
+	redacted := make([]string, 0, len(env))
+	for _, e := range env {
+		name, _, _ := strings.Cut(e, "=")
+		if sensitiveEnvName.MatchString(name) {
+			e = name + "=" + logging.DefaultRedactReplacement
+		}
+		redacted = append(redacted, logging.Redact(e))
+	}
+	sort.Strings(redacted)
+	return redacted
+}
+
+// crashFiles returns files matching the globs that were modified since the
+// run started, so crash files from earlier runs aren't collected again.
+func crashFiles(globs []string, since time.Time) []string {
+	var files []string
+	for _, glob := range globs {
+		matches, _ := filepath.Glob(glob)
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err == nil && info.Mode().IsRegular() && !info.ModTime().Before(since) {
+				files = append(files, m)
+			}
+		}
+	}
+	return files
+}
+
+// pruneCrashBundles removes the service's old bundles. Only files named like
+// its bundles (<service>-YYYYMMDD-HHMMSS[-N].zip) are touched, as the
+// directory may be shared with other services or files.
+func pruneCrashBundles(conf CrashBundleConfig, service string) {
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(service) + `-\d{8}-\d{6}(-\d+)?\.zip$`)
+	entries, _ := os.ReadDir(conf.Dir)
+	type bundle struct {
+		path    string
+		modTime time.Time
+	}
+	var bundles []bundle
+	for _, e := range entries {
+		if e.IsDir() || !pattern.MatchString(e.Name()) {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			bundles = append(bundles, bundle{filepath.Join(conf.Dir, e.Name()), info.ModTime()})
+		}
+	}
+	// Newest first
+	sort.Slice(bundles, func(i, j int) bool {
+		return bundles[i].modTime.After(bundles[j].modTime)
+	})
+	for i, b := range bundles {
+		if (conf.MaxCount > 0 && i >= conf.MaxCount) || (conf.MaxAge > 0 && time.Since(b.modTime) > conf.MaxAge) {
+			_ = os.Remove(b.path)
+		}
+	}
+}
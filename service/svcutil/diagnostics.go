@@ -0,0 +1,118 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package svcutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/lib/osutils"
+	"github.com/papercutsoftware/silver/lib/pathutils"
+	"github.com/papercutsoftware/silver/lib/procmngt"
+)
+
+// Hang diagnostic actions
+const (
+	DiagnosticsSignal  = "signal"  // Ask the service to dump its threads (SIGQUIT or Control-Break) and save its output
+	DiagnosticsCommand = "command" // Run a command such as jstack and save its output
+	DiagnosticsCopy    = "copy"    // Copy a file the service writes
+)
+
+// DiagnosticsConfig is the evidence gathered before a service its monitors
+// found unresponsive is restarted. ${PID} in Path or Args is replaced with the
+// service's process ID, and Path may be a glob pattern.
+type DiagnosticsConfig struct {
+	Action  string        // DiagnosticsSignal, DiagnosticsCommand or DiagnosticsCopy. Empty to disable
+	Path    string        // The command to run, or the file to copy
+	Args    []string      // Command arguments
+	Dir     string        // Where the output is saved
+	Timeout time.Duration // How long the command may run, or how long to collect output after the signal
+}
+
+// hangDiagnostics gathers the diagnostics for the hung process, returning the
+// file they were saved to.
+func (che *crashHandlingExecutable) hangDiagnostics(pid int, buffer *logging.RingBuffer, exited chan struct{}) (string, error) {
+	conf := che.svcConfig.HangDiagnostics
+	if pid == 0 {
+		return "", fmt.Errorf("The service is not running")
+	}
+	if err := os.MkdirAll(conf.Dir, 0o755); err != nil {
+		return "", err
+	}
+	name := filepath.Join(conf.Dir, fmt.Sprintf("%s-%s-%s", strings.TrimSuffix(che.serviceName, ".exe"),
+		conf.Action, time.Now().Format("20060102-150405")))
+	expand := func(s string) string {
+		return strings.ReplaceAll(s, "${PID}", strconv.Itoa(pid))
+	}
+	// Paths may be glob patterns, matched now as the file may be new
+	path := pathutils.FindLastFile(expand(conf.Path))
+
+	switch conf.Action {
+	case DiagnosticsSignal:
+		// The dump is written to the service's output. Collect it until the
+		// timeout, or the process exits (e.g. Go programs exit after the dump)
+		records, cancel := buffer.Subscribe()
+		defer cancel()
+		if err := osutils.ProcessSignalDump(pid); err != nil {
+			return "", err
+		}
+		var out strings.Builder
+		timeout := time.After(conf.Timeout)
+	collect:
+		for {
+			select {
+			case r := <-records:
+				fmt.Fprintf(&out, "%s|%s\n", strings.ToUpper(r.Stream), r.Message)
+			case <-timeout:
+				break collect
+			case <-exited:
+				for len(records) > 0 {
+					r := <-records
+					fmt.Fprintf(&out, "%s|%s\n", strings.ToUpper(r.Stream), r.Message)
+				}
+				break collect
+			}
+		}
+		name += ".txt"
+		return name, os.WriteFile(name, []byte(out.String()), 0o644)
+
+	case DiagnosticsCommand:
+		name += ".txt"
+		out, err := os.Create(name)
+		if err != nil {
+			return "", err
+		}
+		defer out.Close()
+		var args []string
+		for _, a := range conf.Args {
+			args = append(args, expand(a))
+		}
+		exitCode, err := procmngt.NewExecutable(procmngt.ExecConfig{
+			Path:             path,
+			Args:             args,
+			ExecTimeout:      conf.Timeout,
+			GracefulShutDown: time.Second,
+			Stdout:           out,
+			Stderr:           out,
+		}).Execute(nil)
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("%s exited with code %d", conf.Path, exitCode)
+		}
+		return name, err
+
+	case DiagnosticsCopy:
+		name += filepath.Ext(path)
+		return name, osutils.CopyFile(path, name)
+	}
+	return "", fmt.Errorf("Unknown diagnostics action '%s'", conf.Action)
+}
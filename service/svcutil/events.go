@@ -0,0 +1,66 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package svcutil
+
+import "time"
+
+// Event types
+const (
+	EventServiceStarted     = "service-started"
+	EventServiceStopped     = "service-stopped"
+	EventServiceCrashed     = "service-crashed" // Stopped without being asked to
+	EventServiceRestarted   = "service-restarted"
+	EventCrashLimitExceeded = "crash-limit-exceeded"
+	EventMonitorOK          = "monitor-ok"
+	EventMonitorFailed      = "monitor-failed"
+	EventMonitorFlapping    = "monitor-flapping" // Restarted by its monitors too often. Reason is the FlapConfig.Action
+	EventTaskFinished       = "task-finished"
+	EventTaskFailed         = "task-failed"     // Exit code not a success code, or error
+	EventTaskSkipped        = "task-skipped"    // A scheduled run was skipped, the previous run still going. Reason says why
+	EventStartupAborted     = "startup-aborted" // A startup task failed so services weren't started. Reason is the task
+	EventUpdateApplied      = "update-applied"
+	EventReload             = "reload"
+)
+
+// EventServiceRestarted reasons, along with the reason a restart was requested
+const (
+	RestartReasonCrash   = "crash"
+	RestartReasonMonitor = "monitor failed"
+)
+
+// Event is something that happened to a service or task, passed to the
+// OnEvent callback for metrics, status and notifications.
+type Event struct {
+	Type     string
+	Time     time.Time
+	Name     string // Service or task executable name, or the wrapper's service name
+	PID      int    `json:",omitempty"`
+	ExitCode int
+	Reason   string        `json:",omitempty"` // Why a service restarted or the config reloaded
+	Error    string        `json:",omitempty"`
+	URL      string        `json:",omitempty"` // Monitor URL
+	Version  string        `json:",omitempty"` // Installed version after an update
+	Duration time.Duration `json:"-"`          // Task run or monitor ping time
+}
+
+func emit(onEvent func(Event), e Event) {
+	if onEvent == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	onEvent(e)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,157 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package svcutil
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/papercutsoftware/silver/lib/logging"
+)
+
+const (
+	// Lines longer than this are logged in parts
+	maxLineLength = 64 * 1024
+	// Merged multi-line records are logged once they reach this size
+	maxRecordLength = 256 * 1024
+	// A partial line (or merged record) is logged if nothing more arrives within this time
+	partialLineTimeout = time.Second
+)
+
+// logWriter assembles a child process's output into lines and logs each as a
+// record. Incomplete lines are held until the newline arrives, a timeout
+// passes or the process exits (Flush). If continuation is set, lines matching
+// it (e.g. stack trace lines) are merged into the previous line's record.
+// Each line is redacted before it's logged.
+type logWriter struct {
+	sync.Mutex
+	logger       *log.Logger
+	source       string
+	stream       string
+	level        string
+	pid          int64
+	continuation *regexp.Regexp
+	buffer       *logging.RingBuffer // Optional, also keeps recent output
+	buf          []byte              // Incomplete line
+	record       []string            // Lines of the current (possibly multi-line) record
+	recordLen    int
+	timer        *time.Timer
+}
+
+func (l *logWriter) setPID(pid int) {
+	atomic.StoreInt64(&l.pid, int64(pid))
+}
+
+func (l *logWriter) getPID() int {
+	return int(atomic.LoadInt64(&l.pid))
+}
+
+func (l *logWriter) Write(p []byte) (int, error) {
+	if l.logger == nil {
+		return len(p), nil
+	}
+	l.Lock()
+	defer l.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.addLine(string(bytes.TrimSuffix(l.buf[:i], []byte("\r"))))
+		l.buf = l.buf[i+1:]
+	}
+	for len(l.buf) > maxLineLength {
+		n := splitPoint(l.buf, maxLineLength)
+		l.addLine(string(l.buf[:n]))
+		l.buf = l.buf[n:]
+	}
+	// Don't hold on to a large underlying array
+	if len(l.buf) == 0 {
+		l.buf = nil
+	}
+
+	if len(l.buf) > 0 || len(l.record) > 0 {
+		if l.timer == nil {
+			l.timer = time.AfterFunc(partialLineTimeout, l.Flush)
+		} else {
+			l.timer.Reset(partialLineTimeout)
+		}
+	}
+	return len(p), nil
+}
+
+// Flush logs any partial line and pending record. Called when the process
+// exits, and after the partial line timeout.
+func (l *logWriter) Flush() {
+	l.Lock()
+	defer l.Unlock()
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	if len(l.buf) > 0 {
+		l.addLine(string(l.buf))
+		l.buf = nil
+	}
+	l.outputRecord()
+}
+
+func (l *logWriter) addLine(line string) {
+	line = logging.Redact(line)
+	if l.continuation == nil {
+		l.output(line)
+		return
+	}
+	if len(l.record) > 0 && (!l.continuation.MatchString(line) || l.recordLen+len(line) > maxRecordLength) {
+		l.outputRecord()
+	}
+	l.record = append(l.record, line)
+	l.recordLen += len(line) + 1
+}
+
+func (l *logWriter) outputRecord() {
+	if len(l.record) == 0 {
+		return
+	}
+	l.output(strings.Join(l.record, "\n"))
+	l.record = nil
+	l.recordLen = 0
+}
+
+func (l *logWriter) output(line string) {
+	r := logging.Record{
+		Time:    time.Now(),
+		Level:   l.level,
+		Source:  l.source,
+		Stream:  l.stream,
+		PID:     l.getPID(),
+		Message: line,
+	}
+	logging.Output(l.logger, r)
+	if l.buffer != nil {
+		l.buffer.Add(r)
+	}
+}
+
+// splitPoint returns a split position at or before max that doesn't break a
+// UTF-8 character.
+func splitPoint(b []byte, max int) int {
+	for n := max; n > max-utf8.UTFMax && n > 0; n-- {
+		if utf8.RuneStart(b[n]) {
+			return n
+		}
+	}
+	return max
+}
@@ -10,6 +10,14 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/papercutsoftware/silver/lib/logging"
+)
+
+// Monitor policies: when a service with several monitors is restarted
+const (
+	MonitorPolicyAny = "any" // When any monitor fails (default)
+	MonitorPolicyAll = "all" // When all monitors are failing at the same time
 )
 
 type MonitorConfig struct {
@@ -18,65 +26,193 @@ type MonitorConfig struct {
 	Interval              time.Duration
 	Timeout               time.Duration
 	RestartOnFailureCount int
+	HTTP                  HTTPCheck     // http(s) monitors. The TLS options also apply to tls:// and grpcs://
+	Args                  []string      // exec monitors only
+	Echo                  bool          // unix and udp monitors: expect a unique ping to be echoed back
+	Send                  string        // unix and udp monitors: request sent (udp default "ping")
+	Expect                string        // unix and udp monitors: text expected in the response
+	CertExpiryWarning     time.Duration // tls and grpcs monitors: warn when the certificate expires sooner than this
+	FileMaxAge            time.Duration // file monitors: fail if not modified within this, rather than since the last ping
+}
+
+// HTTPCheck is the request made by an http(s) monitor and the response
+// expected back.
+type HTTPCheck struct {
+	Method             string            // Default GET
+	Headers            map[string]string // Values may reference environment variables e.g. "Bearer ${TOKEN}"
+	ExpectedStatus     []string          // Status codes or ranges e.g. "204", "200-299". Default 200
+	BodyPattern        string            // Regular expression the body must match
+	JSONPath           string            // Dotted path (e.g. "checks.db.status" or "items[0].ok") that must exist in the JSON body
+	JSONValue          string            // Value expected at JSONPath, if set
+	Redirects          string            // "follow" (default) or "none" to check the redirect response itself
+	CACertFile         string            // PEM CA certificates trusted for the server certificate
+	ClientCertFile     string            // PEM client certificate and key
+	ClientKeyFile      string
+	InsecureSkipVerify bool   // Only allowed for localhost
+	UnixSocket         string // Connect to this Unix socket instead of the URL's host
+}
+
+func (mc MonitorConfig) enabled() bool {
+	return mc.URL != "" && mc.Interval > 0
 }
 
 type serviceMonitor struct {
 	config      MonitorConfig
 	logger      *log.Logger
 	serviceName string
+	onEvent     func(Event)
+	failing     bool // Failed more than RestartOnFailureCount times in a row. Guarded by monitorGroup.lock
+	state       monitorState
+}
+
+// monitorState is what a monitor remembers between pings. It's reset when
+// the service (re)starts so the new process isn't judged on the old one.
+type monitorState struct {
+	sync.Mutex
+	fileStamp string // file monitors: the size and modification time last seen
+}
+
+func (s *monitorState) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.fileStamp = ""
+}
+
+// monitorGroup checks all of a service's monitors, and decides from the
+// policy when the service has failed.
+type monitorGroup struct {
+	monitors    []*serviceMonitor
+	policy      string
+	logger      *log.Logger
+	serviceName string
+	lock        sync.Mutex
+	changed     chan struct{}
 }
 
-func (sm *serviceMonitor) start(terminate chan struct{}) chan struct{} {
-	monitor := make(chan struct{})
+// start monitors a run of the service until stop is closed, starting afresh
+// (including the startup delay) each run. It returns a channel that's closed
+// when the service has failed.
+func (mg *monitorGroup) start(stop chan struct{}) chan struct{} {
+	failed := make(chan struct{})
+	changed := make(chan struct{}, 1)
+	mg.lock.Lock()
+	mg.changed = changed
+	for _, sm := range mg.monitors {
+		sm.failing = false
+		sm.state.reset()
+	}
+	mg.lock.Unlock()
+	for _, sm := range mg.monitors {
+		go sm.run(stop, mg)
+	}
 	go func() {
-		time.Sleep(sm.config.StartupDelay)
-		failureCount := 0
-		sm.logf("Starting monitor on '%s' (%s)", sm.serviceName, sm.config.URL)
-	isTerminate:
 		for {
 			select {
-			case <-time.After(sm.config.Interval):
-			case <-terminate:
-				break isTerminate
+			case <-stop:
+				return
+			case <-changed:
 			}
-			ok, err := pingURL(sm.config.URL, sm.config.Timeout)
-			if ok {
-				// Did the monitor report another error?
-				if err != nil {
-					sm.logf("%s: Monitor ping error '%v'", sm.serviceName, err)
-				}
-				failureCount = 0
-			} else {
-				failureCount++
-				sm.logf("%s: Monitor detected error - '%v'", sm.serviceName, err)
-			}
-			if failureCount > sm.config.RestartOnFailureCount {
-				sm.logf("%s: Service not responding. Forcing shutdown. (failures: %d)",
-					sm.serviceName, failureCount)
-				break isTerminate
+			if failing := mg.failing(); failing != nil {
+				logf(mg.logger, logging.LevelError, mg.serviceName, "%s: Service not responding. Forcing restart. (failed: %s)",
+					mg.serviceName, strings.Join(failing, ", "))
+				close(failed)
+				return
 			}
-
 		}
-		close(monitor)
 	}()
-	return monitor
+	return failed
 }
 
-func (sm *serviceMonitor) logf(format string, v ...interface{}) {
-	if sm.logger != nil {
-		sm.logger.Printf("%s: %s", sm.serviceName, fmt.Sprintf(format, v...))
+// failing returns the URLs of the failing monitors if the service should be
+// restarted, or nil.
+func (mg *monitorGroup) failing() []string {
+	mg.lock.Lock()
+	defer mg.lock.Unlock()
+	var urls []string
+	for _, sm := range mg.monitors {
+		if sm.failing {
+			urls = append(urls, sm.config.URL)
+		}
+	}
+	if len(urls) == 0 || (mg.policy == MonitorPolicyAll && len(urls) < len(mg.monitors)) {
+		return nil
 	}
+	return urls
 }
 
-var pingFileCache = struct {
-	sync.Mutex
-	m map[string]string
-}{m: make(map[string]string)}
+// setFailing records the monitor's state, ignoring monitors still finishing
+// a ping from a previous run.
+func (mg *monitorGroup) setFailing(sm *serviceMonitor, failing bool, stop chan struct{}) {
+	mg.lock.Lock()
+	if IsClosed(stop) {
+		mg.lock.Unlock()
+		return
+	}
+	changed := sm.failing != failing
+	sm.failing = failing
+	ch := mg.changed
+	mg.lock.Unlock()
+	if changed {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
 
-func pingURL(pingURL string, timeout time.Duration) (ok bool, err error) {
+func (sm *serviceMonitor) run(stop chan struct{}, mg *monitorGroup) {
+	select {
+	case <-time.After(sm.config.StartupDelay):
+	case <-stop:
+		return
+	}
+	failureCount := 0
+	sm.logf(logging.LevelInfo, "Starting monitor on '%s' (%s)", sm.serviceName, sm.config.URL)
+	for {
+		select {
+		case <-time.After(sm.config.Interval):
+		case <-stop:
+			return
+		}
+		pingStart := time.Now()
+		ok, err := pingURL(sm.config, &sm.state)
+		event := Event{Type: EventMonitorOK, Name: sm.serviceName, URL: sm.config.URL,
+			Error: errorString(err), Duration: time.Since(pingStart)}
+		if !ok {
+			event.Type = EventMonitorFailed
+		}
+		emit(sm.onEvent, event)
+		if ok {
+			// Did the monitor report another error?
+			if err != nil {
+				sm.logf(logging.LevelWarn, "%s: Monitor ping error '%v'", sm.serviceName, err)
+			} else {
+				sm.logf(logging.LevelDebug, "%s: Monitor ping OK", sm.serviceName)
+			}
+			failureCount = 0
+		} else {
+			failureCount++
+			sm.logf(logging.LevelWarn, "%s: Monitor detected error - '%v'", sm.serviceName, err)
+		}
+		mg.setFailing(sm, failureCount > sm.config.RestartOnFailureCount, stop)
+	}
+}
+
+func (sm *serviceMonitor) logf(level string, format string, v ...interface{}) {
+	if sm.logger != nil {
+		logging.Output(sm.logger, logging.Record{Level: level, Source: sm.serviceName, Message: fmt.Sprintf(format, v...)})
+	}
+}
+
+func pingURL(config MonitorConfig, state *monitorState) (ok bool, err error) {
+	pingURL, timeout := config.URL, config.Timeout
+	if strings.HasPrefix(strings.ToLower(pingURL), execScheme) {
+		// Windows paths aren't valid URLs
+		return pingExec(pingURL[len(execScheme):], config.Args, timeout)
+	}
 	u, err := url.Parse(pingURL)
 	if err != nil {
-		return true, errors.New("Invalid Ping URL!") // Assume OK
+		return false, fmt.Errorf("Invalid Ping URL: %v", err)
 	}
 	switch strings.ToLower(u.Scheme) {
 	case "tcp":
@@ -86,11 +222,19 @@ func pingURL(pingURL string, timeout time.Duration) (ok bool, err error) {
 	case "http":
 		fallthrough
 	case "https":
-		return pingHTTP(pingURL, timeout)
+		return pingHTTP(pingURL, timeout, config.HTTP)
 	case "file":
-		return pingFile(pingURL)
+		return pingFile(pingURL, config.FileMaxAge, state)
+	case "unix":
+		return pingUnix(unixSocketPath(u), config)
+	case "udp":
+		return pingUDP(u.Host, config)
+	case "tls":
+		return pingTLS(u.Host, config)
+	case "grpc", "grpcs":
+		return pingGRPC(u, config)
 	default:
-		return true, errors.New("Unsupported URL Scheme") // Assume OK
+		return false, fmt.Errorf("Unsupported URL Scheme '%s'", u.Scheme)
 	}
 }
 
@@ -126,21 +270,29 @@ func pingTCPEcho(host string, timeout time.Duration) (ok bool, err error) {
 	return true, nil
 }
 
-func pingFile(fileURL string) (ok bool, err error) {
+func pingFile(fileURL string, maxAge time.Duration, state *monitorState) (ok bool, err error) {
 	file := strings.TrimPrefix(fileURL, "file://")
 	info, err := os.Stat(file)
+	if maxAge > 0 {
+		// Heartbeat file mode
+		if err != nil {
+			return false, err
+		}
+		if age := time.Since(info.ModTime()); age > maxAge {
+			return false, fmt.Errorf("File %s was last modified %s ago", file, age.Round(time.Second))
+		}
+		return true, nil
+	}
 	if err != nil {
 		return true, err
 	}
 	stamp := fmt.Sprintf("%d%d", info.Size(), info.ModTime().UnixNano())
-	pingFileCache.Lock()
-	defer pingFileCache.Unlock()
-	if v, ok := pingFileCache.m[file]; ok {
-		if v == stamp {
-			// No change!
-			return false, errors.New(fmt.Sprintf("File %s did not change", file))
-		}
+	state.Lock()
+	defer state.Unlock()
+	if state.fileStamp == stamp {
+		// No change!
+		return false, errors.New(fmt.Sprintf("File %s did not change", file))
 	}
-	pingFileCache.m[file] = stamp
+	state.fileStamp = stamp
 	return true, nil
 }
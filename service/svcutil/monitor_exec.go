@@ -0,0 +1,48 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package svcutil
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/papercutsoftware/silver/lib/procmngt"
+)
+
+const (
+	execScheme = "exec://"
+	// Output of a failed health check command that's logged
+	maxExecCheckOutput = 4096
+)
+
+// pingExec runs a health check program (e.g. "my-app --healthcheck"). It's
+// healthy if it exits with code 0 within the timeout.
+func pingExec(path string, args []string, timeout time.Duration) (ok bool, err error) {
+	output := &LimitedBuffer{Max: maxExecCheckOutput}
+	execConf := procmngt.ExecConfig{
+		Path:             path,
+		Args:             args,
+		ExecTimeout:      timeout,
+		GracefulShutDown: time.Second,
+		Stdout:           output,
+		Stderr:           output,
+	}
+	start := time.Now()
+	exitCode, err := procmngt.NewExecutable(execConf).Execute(nil)
+	if err != nil {
+		return false, err
+	}
+	if timeout > 0 && time.Since(start) >= timeout {
+		return false, fmt.Errorf("Health check timed out after %s: %s", timeout, bytes.TrimSpace(output.Bytes()))
+	}
+	if exitCode != 0 {
+		return false, fmt.Errorf("Health check exit code %d: %s", exitCode, bytes.TrimSpace(output.Bytes()))
+	}
+	return true, nil
+}
@@ -0,0 +1,306 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package svcutil
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The standard gRPC health checking protocol
+// (https://github.com/grpc/grpc/blob/master/doc/health-checking.md). It's a
+// single unary call, so rather than pull in a gRPC library we speak just
+// enough HTTP/2 to make it.
+const grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+
+// HealthCheckResponse.ServingStatus values
+var grpcServingStatus = []string{"UNKNOWN", "SERVING", "NOT_SERVING", "SERVICE_UNKNOWN"}
+
+const grpcServing = 1
+
+// HTTP/2 frame types and flags used
+const (
+	h2FrameData         = 0x0
+	h2FrameHeaders      = 0x1
+	h2FrameRSTStream    = 0x3
+	h2FrameSettings     = 0x4
+	h2FramePing         = 0x6
+	h2FrameGoAway       = 0x7
+	h2FrameContinuation = 0x9
+	h2FlagEndStream     = 0x1
+	h2FlagAck           = 0x1
+	h2FlagEndHeaders    = 0x4
+	h2FlagPadded        = 0x8
+	h2FlagPriority      = 0x20
+	h2MaxFrameSize      = 16384
+	h2ClientPreface     = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+	h2HealthCheckStream = 1
+)
+
+// pingGRPC calls Health/Check on grpc://host:port/service (plaintext HTTP/2)
+// or grpcs:// (TLS). An empty service checks the server as a whole.
+func pingGRPC(u *url.URL, config MonitorConfig) (ok bool, err error) {
+	var conn net.Conn
+	var warning error
+	scheme := "http"
+	if strings.EqualFold(u.Scheme, "grpcs") {
+		tlsConn, err := dialTLS(u.Host, config, []string{"h2"})
+		if err != nil {
+			return false, err
+		}
+		defer tlsConn.Close()
+		if proto := tlsConn.ConnectionState().NegotiatedProtocol; proto != "h2" {
+			return false, errors.New("The server does not support HTTP/2")
+		}
+		warning = certExpiryWarning(tlsConn, config.CertExpiryWarning)
+		conn, scheme = tlsConn, "https"
+	} else {
+		if conn, err = net.DialTimeout("tcp", u.Host, config.Timeout); err != nil {
+			return false, err
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(config.Timeout))
+	}
+
+	status, err := grpcHealthCheck(conn, scheme, u.Host, strings.Trim(u.Path, "/"))
+	if err != nil {
+		return false, err
+	}
+	if status != grpcServing {
+		name := "UNKNOWN"
+		if status < len(grpcServingStatus) {
+			name = grpcServingStatus[status]
+		}
+		return false, fmt.Errorf("The gRPC health status was %s", name)
+	}
+	return true, warning
+}
+
+// grpcHealthCheck makes the call over the connection and returns the
+// serving status.
+func grpcHealthCheck(conn net.Conn, scheme, authority, service string) (int, error) {
+	var request []byte
+	if service != "" {
+		request = appendProtoString(request, 1, service)
+	}
+	message := make([]byte, 5, 5+len(request))
+	binary.BigEndian.PutUint32(message[1:], uint32(len(request)))
+	message = append(message, request...)
+
+	var headers []byte
+	for _, h := range [][2]string{
+		{":method", "POST"},
+		{":scheme", scheme},
+		{":path", grpcHealthCheckPath},
+		{":authority", authority},
+		{"content-type", "application/grpc"},
+		{"te", "trailers"},
+	} {
+		// Literal header field without indexing, new name
+		headers = append(headers, 0)
+		headers = appendHPACKString(headers, h[0])
+		headers = appendHPACKString(headers, h[1])
+	}
+
+	if _, err := io.WriteString(conn, h2ClientPreface); err != nil {
+		return 0, err
+	}
+	if err := writeH2Frame(conn, h2FrameSettings, 0, 0, nil); err != nil {
+		return 0, err
+	}
+	if err := writeH2Frame(conn, h2FrameHeaders, h2FlagEndHeaders, h2HealthCheckStream, headers); err != nil {
+		return 0, err
+	}
+	if err := writeH2Frame(conn, h2FrameData, h2FlagEndStream, h2HealthCheckStream, message); err != nil {
+		return 0, err
+	}
+
+	var response, block []byte
+	var trailers [][2]string
+	decoder := newHPACKDecoder()
+	endStream := false
+	for {
+		typ, flags, stream, payload, err := readH2Frame(conn)
+		if err != nil {
+			return 0, err
+		}
+		switch typ {
+		case h2FrameSettings:
+			if flags&h2FlagAck == 0 {
+				if err := writeH2Frame(conn, h2FrameSettings, h2FlagAck, 0, nil); err != nil {
+					return 0, err
+				}
+			}
+		case h2FramePing:
+			if flags&h2FlagAck == 0 {
+				if err := writeH2Frame(conn, h2FramePing, h2FlagAck, 0, payload); err != nil {
+					return 0, err
+				}
+			}
+		case h2FrameGoAway:
+			return 0, errors.New("The server closed the connection")
+		case h2FrameRSTStream:
+			if stream == h2HealthCheckStream {
+				return 0, errors.New("The server reset the health check")
+			}
+		case h2FrameData:
+			if flags&h2FlagPadded != 0 && len(payload) > 0 && int(payload[0]) < len(payload) {
+				payload = payload[1 : len(payload)-int(payload[0])]
+			}
+			if stream == h2HealthCheckStream {
+				response = append(response, payload...)
+				endStream = flags&h2FlagEndStream != 0
+			}
+		case h2FrameHeaders, h2FrameContinuation:
+			if typ == h2FrameHeaders {
+				if flags&h2FlagPadded != 0 && len(payload) > 0 && int(payload[0]) < len(payload) {
+					payload = payload[1 : len(payload)-int(payload[0])]
+				}
+				if flags&h2FlagPriority != 0 && len(payload) >= 5 {
+					payload = payload[5:]
+				}
+				endStream = stream == h2HealthCheckStream && flags&h2FlagEndStream != 0
+			}
+			// Header blocks are always decoded so the HPACK table stays in step
+			block = append(block, payload...)
+			if flags&h2FlagEndHeaders == 0 {
+				continue
+			}
+			fields, err := decoder.decode(block)
+			if err != nil {
+				return 0, err
+			}
+			block = block[:0]
+			if endStream {
+				trailers = fields
+			}
+		}
+		if endStream && len(block) == 0 {
+			break
+		}
+	}
+
+	// The call's outcome is in the trailers. A trailers-only response carries
+	// the error e.g. the health service or the named service isn't registered.
+	code, reason := "", ""
+	for _, field := range trailers {
+		switch field[0] {
+		case "grpc-status":
+			code = field[1]
+		case "grpc-message":
+			reason = field[1]
+			if unescaped, err := url.PathUnescape(reason); err == nil {
+				reason = unescaped
+			}
+		}
+	}
+	switch {
+	case code == "":
+		return 0, errors.New("The server returned no gRPC status")
+	case code != "0" && reason != "":
+		return 0, fmt.Errorf("The gRPC health check failed with status %s: %s", code, reason)
+	case code != "0":
+		return 0, fmt.Errorf("The gRPC health check failed with status %s", code)
+	}
+	return parseGRPCHealthResponse(response)
+}
+
+func parseGRPCHealthResponse(response []byte) (int, error) {
+	if len(response) < 5 {
+		return 0, errors.New("The server returned no gRPC health status")
+	}
+	if response[0] != 0 {
+		return 0, errors.New("Compressed gRPC responses are not supported")
+	}
+	n := binary.BigEndian.Uint32(response[1:5])
+	if uint32(len(response)-5) < n {
+		return 0, errors.New("Truncated gRPC response")
+	}
+	message := response[5 : 5+n]
+	status := 0
+	for len(message) > 0 {
+		key, k := binary.Uvarint(message)
+		if k <= 0 {
+			return 0, errors.New("Invalid gRPC health response")
+		}
+		message = message[k:]
+		switch key & 7 {
+		case 0: // varint
+			v, k := binary.Uvarint(message)
+			if k <= 0 {
+				return 0, errors.New("Invalid gRPC health response")
+			}
+			if key>>3 == 1 {
+				status = int(v)
+			}
+			message = message[k:]
+		case 2: // length delimited
+			l, k := binary.Uvarint(message)
+			if k <= 0 || uint64(len(message)-k) < l {
+				return 0, errors.New("Invalid gRPC health response")
+			}
+			message = message[k+int(l):]
+		default:
+			return 0, errors.New("Invalid gRPC health response")
+		}
+	}
+	return status, nil
+}
+
+func writeH2Frame(w io.Writer, typ, flags byte, stream uint32, payload []byte) error {
+	frame := make([]byte, 9, 9+len(payload))
+	frame[0], frame[1], frame[2] = byte(len(payload)>>16), byte(len(payload)>>8), byte(len(payload))
+	frame[3], frame[4] = typ, flags
+	binary.BigEndian.PutUint32(frame[5:], stream)
+	_, err := w.Write(append(frame, payload...))
+	return err
+}
+
+func readH2Frame(r io.Reader) (typ, flags byte, stream uint32, payload []byte, err error) {
+	var header [9]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	length := int(header[0])<<16 | int(header[1])<<8 | int(header[2])
+	if length > h2MaxFrameSize {
+		err = fmt.Errorf("HTTP/2 frame too large (%d bytes)", length)
+		return
+	}
+	typ, flags = header[3], header[4]
+	stream = binary.BigEndian.Uint32(header[5:]) & 0x7fffffff
+	payload = make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	return
+}
+
+// appendHPACKString appends a string literal without Huffman coding.
+func appendHPACKString(b []byte, s string) []byte {
+	n := len(s)
+	if n < 127 {
+		b = append(b, byte(n))
+	} else {
+		b = append(b, 127)
+		for n -= 127; n >= 128; n /= 128 {
+			b = append(b, byte(n%128+128))
+		}
+		b = append(b, byte(n))
+	}
+	return append(b, s...)
+}
+
+func appendProtoString(b []byte, field int, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|2))
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
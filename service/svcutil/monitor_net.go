@@ -0,0 +1,166 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package svcutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/papercutsoftware/silver/lib/osutils"
+)
+
+// Responses larger than this are truncated before checking
+const maxPingResponse = 1024
+
+// unixSocketPath returns the socket path of a unix: URL. The path may be
+// absolute (unix:///var/run/app.sock) or relative to the working directory
+// (unix://run/app.sock or unix:run/app.sock).
+func unixSocketPath(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	return u.Host + u.Path
+}
+
+// pingUnix connects to the Unix socket and, if a request or response is
+// configured, checks the exchange.
+func pingUnix(path string, config MonitorConfig) (ok bool, err error) {
+	conn, err := net.DialTimeout("unix", path, config.Timeout)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if !config.Echo && config.Send == "" && config.Expect == "" {
+		return true, nil
+	}
+	return exchangePing(conn, config, "")
+}
+
+// pingUDP sends a datagram and checks the response. UDP is connectionless
+// so there's always an exchange.
+func pingUDP(host string, config MonitorConfig) (ok bool, err error) {
+	conn, err := net.DialTimeout("udp", host, config.Timeout)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	return exchangePing(conn, config, "ping")
+}
+
+// exchangePing sends the configured request (or a unique ping to echo) and
+// checks the response contains what's expected. An empty Expect accepts any
+// response.
+func exchangePing(conn net.Conn, config MonitorConfig, defaultSend string) (ok bool, err error) {
+	conn.SetDeadline(time.Now().Add(config.Timeout))
+	send, expect := config.Send, config.Expect
+	if send == "" {
+		send = defaultSend
+	}
+	if config.Echo {
+		send = fmt.Sprintf("ping-%d", time.Now().UTC().UnixNano())
+		expect = send
+	}
+	if send != "" {
+		if _, err := conn.Write([]byte(send)); err != nil {
+			return false, err
+		}
+	}
+	buf := make([]byte, maxPingResponse)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return false, err
+	}
+	if !strings.Contains(string(buf[:n]), expect) {
+		if config.Echo {
+			return false, errors.New("Server did not echo")
+		}
+		return false, fmt.Errorf("The response did not contain '%s'", expect)
+	}
+	return true, nil
+}
+
+// pingTLS completes a TLS handshake. The check passes with a warning when the
+// server's certificate expires within CertExpiryWarning.
+func pingTLS(host string, config MonitorConfig) (ok bool, err error) {
+	conn, err := dialTLS(host, config, nil)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	return true, certExpiryWarning(conn, config.CertExpiryWarning)
+}
+
+func dialTLS(host string, config MonitorConfig, nextProtos []string) (*tls.Conn, error) {
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return nil, err
+	}
+	if config.HTTP.InsecureSkipVerify && !osutils.IsLocalhost(hostname) {
+		return nil, errors.New("Skipping certificate verification is only allowed for localhost")
+	}
+	tlsConfig, err := monitorTLSConfig(config.HTTP)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.NextProtos = nextProtos
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", host, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(config.Timeout))
+	return conn, nil
+}
+
+func certExpiryWarning(conn *tls.Conn, warning time.Duration) error {
+	certs := conn.ConnectionState().PeerCertificates
+	if warning <= 0 || len(certs) == 0 {
+		return nil
+	}
+	if expires := certs[0].NotAfter; time.Until(expires) < warning {
+		return fmt.Errorf("The certificate for %s expires %s", certs[0].Subject.CommonName, expires.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// monitorTLSConfig returns the TLS config for the check's certificate
+// options, or nil if there are none.
+func monitorTLSConfig(check HTTPCheck) (*tls.Config, error) {
+	if check.CACertFile == "" && check.ClientCertFile == "" && !check.InsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: check.InsecureSkipVerify}
+	if check.CACertFile != "" {
+		pem, err := os.ReadFile(check.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in %s", check.CACertFile)
+		}
+	}
+	if check.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(check.ClientCertFile, check.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -8,9 +8,21 @@
 package svcutil_test
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,13 +45,17 @@ func Test_ExecuteService_MonitorConfig_EchoPing(t *testing.T) {
 			Timeout:      1 * time.Second,
 		},
 	}
+	terminate, restarted := terminateOnMonitorRestart(&serviceConf, 15*time.Second)
 	start := time.Now()
 
 	// Act
-	svcutil.ExecuteService(nil, serviceConf)
+	svcutil.ExecuteService(terminate, serviceConf)
 
 	// Assert
 	elapsed := time.Since(start)
+	if !restarted() {
+		t.Fatalf("Expected the monitor to restart the service")
+	}
 	expected := 4500 * time.Millisecond //3500 +  1000 (for go routine to start)
 	threshold := 3500 * time.Millisecond //to allow for some variance based on machine speed
 	if elapsed > expected+threshold {
@@ -73,3 +89,612 @@ func makeTestExe(t *testing.T, testSrc string) (tmpDir, testExe string) {
 	return tmpDir, exe
 }
 */
+
+func Test_ExecuteService_Monitors_Policy(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(tmpDir)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	monitors := []svcutil.MonitorConfig{
+		{URL: "tcp://" + listener.Addr().String(), Interval: 100 * time.Millisecond, Timeout: time.Second},
+		{URL: "tcp://" + closed.Addr().String(), Interval: 100 * time.Millisecond, Timeout: time.Second},
+	}
+
+	for _, policy := range []string{svcutil.MonitorPolicyAny, svcutil.MonitorPolicyAll} {
+		serviceConf := svcutil.ServiceConfig{
+			Path:          testExe,
+			Monitors:      monitors,
+			MonitorPolicy: policy,
+		}
+		terminate, restarted := terminateOnMonitorRestart(&serviceConf, 2*time.Second)
+
+		// Act
+		svcutil.ExecuteService(terminate, serviceConf)
+
+		// Assert
+		if policy == svcutil.MonitorPolicyAny && !restarted() {
+			t.Errorf("Expected the service to be restarted when any monitor fails")
+		}
+		if policy == svcutil.MonitorPolicyAll && restarted() {
+			t.Errorf("Expected the service to keep running while a monitor is OK")
+		}
+	}
+}
+
+func Test_ExecuteService_Monitor_HTTPCheck(t *testing.T) {
+	// Arrange
+	t.Setenv("TEST_HEALTH_TOKEN", "secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	check := svcutil.HTTPCheck{
+		Method:         http.MethodHead,
+		Headers:        map[string]string{"Authorization": "Bearer ${TEST_HEALTH_TOKEN}"},
+		ExpectedStatus: []string{"200", "204-206"},
+	}
+
+	// Act & Assert
+	if monitorRestartsService(t, svcutil.MonitorConfig{URL: server.URL, HTTP: check}) {
+		t.Errorf("Expected the health check to pass")
+	}
+	check.Headers = nil
+	if !monitorRestartsService(t, svcutil.MonitorConfig{URL: server.URL, HTTP: check}) {
+		t.Errorf("Expected the health check to fail without the auth header")
+	}
+}
+
+func Test_ExecuteService_Monitor_HTTPCheck_JSONPath(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status": "UP", "checks": [{"name": "db", "status": "DOWN"}]}`)
+	}))
+	defer server.Close()
+
+	// Act & Assert
+	check := svcutil.HTTPCheck{JSONPath: "status", JSONValue: "UP", BodyPattern: `"db"`}
+	if monitorRestartsService(t, svcutil.MonitorConfig{URL: server.URL, HTTP: check}) {
+		t.Errorf("Expected the health check to pass")
+	}
+	check = svcutil.HTTPCheck{JSONPath: "checks[0].status", JSONValue: "UP"}
+	if !monitorRestartsService(t, svcutil.MonitorConfig{URL: server.URL, HTTP: check}) {
+		t.Errorf("Expected the health check to fail on the db status")
+	}
+}
+
+func Test_ExecuteService_Monitor_HTTPCheck_UnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets not tested on Windows")
+	}
+	// Arrange
+	socket := filepath.Join(t.TempDir(), "health.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// Act & Assert
+	mc := svcutil.MonitorConfig{URL: "http://localhost/health", HTTP: svcutil.HTTPCheck{UnixSocket: socket}}
+	if monitorRestartsService(t, mc) {
+		t.Errorf("Expected the health check over the Unix socket to pass")
+	}
+}
+
+// monitorRestartsService runs a service with the monitor, returning true if the
+// monitor restarted it within a couple of seconds.
+func monitorRestartsService(t *testing.T, mc svcutil.MonitorConfig) bool {
+	tmpDir, testExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(tmpDir)
+	mc.Interval = 100 * time.Millisecond
+	mc.Timeout = time.Second
+	serviceConf := svcutil.ServiceConfig{
+		Path:     testExe,
+		Monitors: []svcutil.MonitorConfig{mc},
+	}
+	terminate, restarted := terminateOnMonitorRestart(&serviceConf, 1500*time.Millisecond)
+
+	svcutil.ExecuteService(terminate, serviceConf)
+
+	return restarted()
+}
+
+// terminateOnMonitorRestart returns a terminate channel for the service that's
+// closed when its monitors first restart it, or after the timeout.
+func terminateOnMonitorRestart(serviceConf *svcutil.ServiceConfig, timeout time.Duration) (terminate chan struct{}, restarted func() bool) {
+	terminate = make(chan struct{})
+	var once sync.Once
+	var monitorRestarted atomic.Bool
+	serviceConf.OnEvent = func(e svcutil.Event) {
+		if e.Type == svcutil.EventServiceRestarted && e.Reason == svcutil.RestartReasonMonitor {
+			monitorRestarted.Store(true)
+			once.Do(func() { close(terminate) })
+		}
+	}
+	time.AfterFunc(timeout, func() { once.Do(func() { close(terminate) }) })
+	return terminate, monitorRestarted.Load
+}
+
+func Test_ExecuteService_Monitor_Exec(t *testing.T) {
+	// Arrange
+	okDir, okExe := makeHelloWorldExe(t)
+	defer os.RemoveAll(okDir)
+	failDir, failExe := makeCrashExe(t)
+	defer os.RemoveAll(failDir)
+
+	// Act & Assert
+	if monitorRestartsService(t, svcutil.MonitorConfig{URL: "exec://" + okExe}) {
+		t.Errorf("Expected the health check to pass on exit code 0")
+	}
+
+	var logBuf bytes.Buffer
+	tmpDir, testExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(tmpDir)
+	serviceConf := svcutil.ServiceConfig{
+		Path:   testExe,
+		Logger: log.New(&logBuf, "", 0),
+		Monitors: []svcutil.MonitorConfig{
+			{URL: "exec://" + failExe, Interval: 100 * time.Millisecond, Timeout: time.Second},
+		},
+	}
+	terminate, _ := terminateOnMonitorRestart(&serviceConf, 5*time.Second)
+	svcutil.ExecuteService(terminate, serviceConf)
+	if !strings.Contains(logBuf.String(), "Health check exit code 1: CRASHED!") {
+		t.Errorf("Expected the health check output to be logged, got:\n%s", logBuf.String())
+	}
+}
+
+func Test_ExecuteService_Monitor_Exec_Timeout(t *testing.T) {
+	// Arrange
+	hungDir, hungExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(hungDir)
+	var logBuf bytes.Buffer
+	tmpDir, testExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(tmpDir)
+	serviceConf := svcutil.ServiceConfig{
+		Path:   testExe,
+		Logger: log.New(&logBuf, "", 0),
+		Monitors: []svcutil.MonitorConfig{
+			{URL: "exec://" + hungExe, Interval: 100 * time.Millisecond, Timeout: 500 * time.Millisecond},
+		},
+	}
+	terminate, restarted := terminateOnMonitorRestart(&serviceConf, 10*time.Second)
+
+	// Act
+	svcutil.ExecuteService(terminate, serviceConf)
+
+	// Assert
+	if !restarted() {
+		t.Errorf("Expected a health check that doesn't exit to fail and restart the service")
+	}
+	if !strings.Contains(logBuf.String(), "Health check timed out after 500ms") {
+		t.Errorf("Expected the health check timeout to be logged, got:\n%s", logBuf.String())
+	}
+}
+
+func Test_ExecuteService_Monitor_UnsupportedScheme(t *testing.T) {
+	if !monitorRestartsService(t, svcutil.MonitorConfig{URL: "ftp://localhost:21"}) {
+		t.Errorf("Expected an unsupported scheme to fail rather than assume OK")
+	}
+}
+
+func Test_ExecuteService_Monitor_UnixEcho(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets not tested on Windows")
+	}
+	// Arrange
+	socket := filepath.Join(t.TempDir(), "echo.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 1024)
+			n, _ := conn.Read(buf)
+			conn.Write(buf[:n])
+			conn.Close()
+		}
+	}()
+
+	// Act & Assert
+	if monitorRestartsService(t, svcutil.MonitorConfig{URL: "unix://" + socket, Echo: true}) {
+		t.Errorf("Expected the Unix socket echo to pass")
+	}
+	if !monitorRestartsService(t, svcutil.MonitorConfig{URL: "unix://" + socket, Send: "status", Expect: "OK"}) {
+		t.Errorf("Expected the check to fail when the response doesn't contain 'OK'")
+	}
+}
+
+func Test_ExecuteService_Monitor_UnixRelativePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets not tested on Windows")
+	}
+	// Arrange
+	tmpDir, testExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(tmpDir)
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Mkdir("run", 0755); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("unix", "run/app.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	for _, url := range []string{"unix://run/app.sock", "unix:run/app.sock"} {
+		serviceConf := svcutil.ServiceConfig{
+			Path: testExe,
+			Monitors: []svcutil.MonitorConfig{
+				{URL: url, Interval: 100 * time.Millisecond, Timeout: time.Second},
+			},
+		}
+		terminate, restarted := terminateOnMonitorRestart(&serviceConf, 1500*time.Millisecond)
+
+		// Act
+		svcutil.ExecuteService(terminate, serviceConf)
+
+		// Assert
+		if restarted() {
+			t.Errorf("Expected %s to connect to the socket relative to the working directory", url)
+		}
+	}
+}
+
+func Test_ExecuteService_Monitor_UDP(t *testing.T) {
+	// Arrange
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if string(buf[:n]) == "status" {
+				conn.WriteTo([]byte("status: OK"), addr)
+			}
+		}
+	}()
+	url := "udp://" + conn.LocalAddr().String()
+
+	// Act & Assert
+	if monitorRestartsService(t, svcutil.MonitorConfig{URL: url, Send: "status", Expect: "OK"}) {
+		t.Errorf("Expected the UDP check to pass")
+	}
+	if !monitorRestartsService(t, svcutil.MonitorConfig{URL: url}) {
+		t.Errorf("Expected the UDP check to fail without a response")
+	}
+}
+
+func Test_ExecuteService_Monitor_TLS_CertExpiryWarning(t *testing.T) {
+	// Arrange
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	url := "tls://" + server.Listener.Addr().String()
+	tmpDir, testExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(tmpDir)
+	var logBuf bytes.Buffer
+	serviceConf := svcutil.ServiceConfig{
+		Path:   testExe,
+		Logger: log.New(&logBuf, "", 0),
+		Monitors: []svcutil.MonitorConfig{{
+			URL:               url,
+			Interval:          100 * time.Millisecond,
+			Timeout:           time.Second,
+			HTTP:              svcutil.HTTPCheck{InsecureSkipVerify: true},
+			CertExpiryWarning: 100 * 365 * 24 * time.Hour,
+		}},
+	}
+	terminate := make(chan struct{})
+	time.AfterFunc(time.Second, func() { close(terminate) })
+
+	// Act
+	svcutil.ExecuteService(terminate, serviceConf)
+
+	// Assert
+	if !strings.Contains(logBuf.String(), "Monitor ping error 'The certificate for") {
+		t.Errorf("Expected a certificate expiry warning, got:\n%s", logBuf.String())
+	}
+	if monitorRestartsService(t, svcutil.MonitorConfig{URL: url, HTTP: svcutil.HTTPCheck{InsecureSkipVerify: true}}) {
+		t.Errorf("Expected the TLS handshake to pass")
+	}
+	if !monitorRestartsService(t, svcutil.MonitorConfig{URL: url}) {
+		t.Errorf("Expected the TLS check to fail on an untrusted certificate")
+	}
+}
+
+func Test_ExecuteService_Monitor_GRPCHealth(t *testing.T) {
+	// Arrange
+	var status atomic.Int32
+	status.Store(1) // SERVING
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.URL.Path != "/grpc.health.v1.Health/Check" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write([]byte{0, 0, 0, 0, 2, 0x08, byte(status.Load())})
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	mc := svcutil.MonitorConfig{
+		URL:  "grpcs://" + server.Listener.Addr().String(),
+		HTTP: svcutil.HTTPCheck{InsecureSkipVerify: true},
+	}
+
+	// Act & Assert
+	if monitorRestartsService(t, mc) {
+		t.Errorf("Expected the gRPC health check to pass")
+	}
+	status.Store(2) // NOT_SERVING
+	if !monitorRestartsService(t, mc) {
+		t.Errorf("Expected the gRPC health check to fail when not serving")
+	}
+}
+
+func Test_ExecuteService_Monitor_GRPCStatusTrailer(t *testing.T) {
+	// Arrange
+	var grpcStatus atomic.Value
+	grpcStatus.Store("0")
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.Write([]byte{0, 0, 0, 0, 2, 0x08, 1}) // SERVING
+		w.Header().Set("Grpc-Status", grpcStatus.Load().(string))
+		w.Header().Set("Grpc-Message", "service%20unavailable")
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	mc := svcutil.MonitorConfig{
+		URL:  "grpcs://" + server.Listener.Addr().String(),
+		HTTP: svcutil.HTTPCheck{InsecureSkipVerify: true},
+	}
+
+	// Act & Assert
+	if monitorRestartsService(t, mc) {
+		t.Errorf("Expected the gRPC health check to pass with an OK status")
+	}
+	grpcStatus.Store("14") // UNAVAILABLE
+	if !monitorRestartsService(t, mc) {
+		t.Errorf("Expected the gRPC health check to fail when the call's status isn't OK")
+	}
+}
+
+func Test_ExecuteService_Monitor_FileMaxAge(t *testing.T) {
+	// Arrange
+	heartbeat := filepath.Join(t.TempDir(), "heartbeat")
+	if err := os.WriteFile(heartbeat, []byte("beat"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mc := svcutil.MonitorConfig{URL: "file://" + heartbeat, FileMaxAge: time.Minute}
+
+	// Act & Assert
+	if monitorRestartsService(t, mc) {
+		t.Errorf("Expected the heartbeat to pass while it's recent, even though it didn't change")
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(heartbeat, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if !monitorRestartsService(t, mc) {
+		t.Errorf("Expected the heartbeat to fail once it's older than the max age")
+	}
+}
+
+func Test_ExecuteService_Monitor_FileState_PerService(t *testing.T) {
+	// Arrange
+	file := filepath.Join(t.TempDir(), "progress")
+	if err := os.WriteFile(file, []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for i := 2; ; i++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(50 * time.Millisecond):
+				os.WriteFile(file, []byte(fmt.Sprint(i)), 0644)
+			}
+		}
+	}()
+	mc := svcutil.MonitorConfig{URL: "file://" + file, RestartOnFailureCount: 1}
+
+	// Act: two services watching the same file
+	restarted := make(chan bool)
+	for i := 0; i < 2; i++ {
+		go func() { restarted <- monitorRestartsService(t, mc) }()
+	}
+
+	// Assert
+	for i := 0; i < 2; i++ {
+		if <-restarted {
+			t.Errorf("Expected monitors of the same file not to interfere")
+		}
+	}
+}
+
+func Test_ExecuteService_Monitor_RestartsInPlace_UntilFlapping(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(tmpDir)
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	var lock sync.Mutex
+	var starts []time.Time
+	var flapping []svcutil.Event
+	serviceConf := svcutil.ServiceConfig{
+		Path: testExe,
+		Monitors: []svcutil.MonitorConfig{{
+			URL:          "tcp://" + closed.Addr().String(),
+			StartupDelay: 500 * time.Millisecond,
+			Interval:     100 * time.Millisecond,
+			Timeout:      time.Second,
+		}},
+		MonitorFlap: svcutil.FlapConfig{Count: 3, Window: time.Minute, Action: svcutil.FlapActionStop},
+		OnEvent: func(e svcutil.Event) {
+			lock.Lock()
+			defer lock.Unlock()
+			switch e.Type {
+			case svcutil.EventServiceStarted:
+				starts = append(starts, time.Now())
+			case svcutil.EventMonitorFlapping:
+				flapping = append(flapping, e)
+			}
+		},
+	}
+	terminate := make(chan struct{})
+	timer := time.AfterFunc(15*time.Second, func() { close(terminate) })
+	defer timer.Stop()
+
+	// Act
+	err = svcutil.ExecuteService(terminate, serviceConf)
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "flapping") {
+		t.Fatalf("Expected the service to be stopped for flapping, got: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(starts) != 3 {
+		t.Errorf("Expected the service to be restarted in place twice, got %d starts", len(starts))
+	}
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 500*time.Millisecond {
+			t.Errorf("Expected the monitor startup delay after each restart, restarted after %v", gap)
+		}
+	}
+	if len(flapping) != 1 || flapping[0].Reason != svcutil.FlapActionStop {
+		t.Errorf("Expected a monitor-flapping event, got %+v", flapping)
+	}
+}
+
+func Test_ExecuteService_Monitor_HangDiagnostics(t *testing.T) {
+	// Arrange
+	cmdDir, cmdExe := makeHelloWorldExe(t)
+	defer os.RemoveAll(cmdDir)
+	tmpDir, testExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(tmpDir)
+	diagnosticsDir := filepath.Join(t.TempDir(), "diagnostics")
+	serviceConf := svcutil.ServiceConfig{
+		Path: testExe,
+		Monitors: []svcutil.MonitorConfig{
+			{URL: "ftp://localhost:21", Interval: 100 * time.Millisecond, Timeout: time.Second},
+		},
+		HangDiagnostics: svcutil.DiagnosticsConfig{
+			Action:  svcutil.DiagnosticsCommand,
+			Path:    cmdExe,
+			Args:    []string{"pid-${PID}"},
+			Dir:     diagnosticsDir,
+			Timeout: 5 * time.Second,
+		},
+	}
+	terminate, restarted := terminateOnMonitorRestart(&serviceConf, 10*time.Second)
+
+	// Act
+	svcutil.ExecuteService(terminate, serviceConf)
+
+	// Assert
+	if !restarted() {
+		t.Fatalf("Expected the monitor to restart the service")
+	}
+	files, _ := filepath.Glob(filepath.Join(diagnosticsDir, "*-command-*.txt"))
+	if len(files) != 1 {
+		t.Fatalf("Expected a diagnostics file, got %v", files)
+	}
+	output, _ := os.ReadFile(files[0])
+	if !regexp.MustCompile(`Hello pid-[1-9][0-9]*!`).Match(output) {
+		t.Errorf("Expected the command output with the service PID, got %q", output)
+	}
+}
+
+func Test_ExecuteService_Monitor_HangDiagnostics_Signal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Control-Break thread dumps not tested on Windows")
+	}
+	// Arrange
+	tmpDir, testExe := makeHelloForeverExe(t)
+	defer os.RemoveAll(tmpDir)
+	diagnosticsDir := filepath.Join(t.TempDir(), "diagnostics")
+	serviceConf := svcutil.ServiceConfig{
+		Path: testExe,
+		Monitors: []svcutil.MonitorConfig{
+			{URL: "ftp://localhost:21", Interval: 100 * time.Millisecond, Timeout: time.Second},
+		},
+		// Output is only kept when logged
+		Logger:      log.New(io.Discard, "", 0),
+		ErrorLogger: log.New(io.Discard, "", 0),
+		HangDiagnostics: svcutil.DiagnosticsConfig{
+			Action:  svcutil.DiagnosticsSignal,
+			Dir:     diagnosticsDir,
+			Timeout: time.Second,
+		},
+	}
+	// Go programs exit after dumping their goroutines on SIGQUIT, so this is
+	// a crash rather than a monitor restart
+	terminate := make(chan struct{})
+	time.AfterFunc(2*time.Second, func() { close(terminate) })
+
+	// Act
+	svcutil.ExecuteService(terminate, serviceConf)
+
+	// Assert
+	files, _ := filepath.Glob(filepath.Join(diagnosticsDir, "*-signal-*.txt"))
+	if len(files) == 0 {
+		t.Fatalf("Expected a diagnostics file, got %v", files)
+	}
+	output, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(output), "goroutine") {
+		t.Errorf("Expected the goroutine dump, got %q", output)
+	}
+}
@@ -9,32 +9,156 @@
 package svcutil
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/papercutsoftware/silver/lib/osutils"
 )
 
-func pingHTTP(pingURL string, timeout time.Duration) (ok bool, err error) {
-	client := httpClientWithTimeout(timeout)
-	resp, err := client.Get(pingURL)
+// Response bodies larger than this aren't checked
+const maxHealthBodySize = 1024 * 1024
+
+func pingHTTP(pingURL string, timeout time.Duration, check HTTPCheck) (ok bool, err error) {
+	client, err := httpClientWithTimeout(timeout, check)
+	if err != nil {
+		return false, err
+	}
+	method := check.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, pingURL, nil)
+	if err != nil {
+		return false, err
+	}
+	if check.InsecureSkipVerify && !osutils.IsLocalhost(req.URL.Hostname()) {
+		return false, errors.New("Skipping certificate verification is only allowed for localhost")
+	}
+	for k, v := range check.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = os.ExpandEnv(v)
+			continue
+		}
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return false, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return false, errors.New("The HTTP status was not 200 OK")
+	if !expectedStatus(resp.StatusCode, check.ExpectedStatus) {
+		if len(check.ExpectedStatus) == 0 {
+			return false, errors.New("The HTTP status was not 200 OK")
+		}
+		return false, fmt.Errorf("The HTTP status %d was not one of %s", resp.StatusCode, strings.Join(check.ExpectedStatus, ", "))
+	}
+	if check.BodyPattern == "" && check.JSONPath == "" {
+		if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+			return false, err
+		}
+		return true, nil // OK
 	}
-	if _, err := io.Copy(ioutil.Discard, resp.Body); err != nil {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthBodySize))
+	if err != nil {
 		return false, err
 	}
+	if check.BodyPattern != "" {
+		re, err := regexp.Compile(check.BodyPattern)
+		if err != nil {
+			return false, err
+		}
+		if !re.Match(body) {
+			return false, fmt.Errorf("The response did not match '%s'", check.BodyPattern)
+		}
+	}
+	if check.JSONPath != "" {
+		if err := checkJSONPath(body, check.JSONPath, check.JSONValue); err != nil {
+			return false, err
+		}
+	}
 	return true, nil // OK
 }
 
-func httpClientWithTimeout(timeout time.Duration) *http.Client {
+// expectedStatus returns true if the status matches one of the expected codes
+// or ranges (e.g. "204" or "200-299"), or is 200 if none are given.
+func expectedStatus(status int, expected []string) bool {
+	if len(expected) == 0 {
+		return status == http.StatusOK
+	}
+	for _, e := range expected {
+		from, to, isRange := strings.Cut(e, "-")
+		min, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			continue
+		}
+		max := min
+		if isRange {
+			if max, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+				continue
+			}
+		}
+		if status >= min && status <= max {
+			return true
+		}
+	}
+	return false
+}
+
+// checkJSONPath checks the value at the dotted path (e.g. "checks.db.status"
+// or "items[0].ok") exists and, if expected is set, equals it.
+func checkJSONPath(body []byte, path, expected string) error {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("The response is not JSON: %v", err)
+	}
+	for _, part := range strings.Split(strings.ReplaceAll(path, "[", ".["), ".") {
+		if part == "" {
+			continue
+		}
+		if strings.HasPrefix(part, "[") && strings.HasSuffix(part, "]") {
+			i, err := strconv.Atoi(part[1 : len(part)-1])
+			list, ok := v.([]interface{})
+			if err != nil || !ok || i < 0 || i >= len(list) {
+				return fmt.Errorf("The response has no %s", path)
+			}
+			v = list[i]
+			continue
+		}
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("The response has no %s", path)
+		}
+		if v, ok = obj[part]; !ok {
+			return fmt.Errorf("The response has no %s", path)
+		}
+	}
+	if expected == "" {
+		return nil
+	}
+	actual := fmt.Sprint(v)
+	if v == nil {
+		actual = "null"
+	}
+	if actual != expected {
+		return fmt.Errorf("The response %s was '%s', expected '%s'", path, actual, expected)
+	}
+	return nil
+}
+
+func httpClientWithTimeout(timeout time.Duration, check HTTPCheck) (*http.Client, error) {
 	tdial := func(network, addr string) (conn net.Conn, err error) {
+		if check.UnixSocket != "" {
+			network, addr = "unix", check.UnixSocket
+		}
 		conn, err = net.DialTimeout(network, addr, timeout)
 		if err != nil {
 			return nil, err
@@ -42,10 +166,22 @@ func httpClientWithTimeout(timeout time.Duration) *http.Client {
 		conn.SetDeadline(time.Now().Add(timeout))
 		return conn, err
 	}
+	tlsConfig, err := monitorTLSConfig(check)
+	if err != nil {
+		return nil, err
+	}
 
-	return &http.Client{
+	client := &http.Client{
 		Transport: &http.Transport{
-			Dial: tdial,
+			Dial:              tdial,
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
 		},
 	}
+	if check.Redirects == "none" {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client, nil
 }
@@ -13,6 +13,6 @@ import (
 	"time"
 )
 
-func pingHTTP(pingURL string, timeout time.Duration) (ok bool, err error) {
+func pingHTTP(pingURL string, timeout time.Duration, check HTTPCheck) (ok bool, err error) {
 	return true, errors.New("HTTP monitoring is not supported in this version. Use the full version")
 }
@@ -8,7 +8,6 @@
 package svcutil
 
 import (
-	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
@@ -16,11 +15,25 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/papercutsoftware/silver/lib/fswatch"
+	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/lib/osutils"
 	"github.com/papercutsoftware/silver/lib/procmngt"
 )
 
+const (
+	// stopFileEnvVar tells a service where to create its stop file
+	stopFileEnvVar = "SILVER_SERVICE_STOP_FILE"
+	stopFilePoll   = 5 * time.Second
+	// Lines of output logged when a service stops unexpectedly
+	crashOutputLines = 50
+)
+
 var (
 	random *rand.Rand
 )
@@ -45,6 +58,27 @@ type TaskConfig struct {
 	GracefulShutDown   time.Duration
 	Logger             *log.Logger
 	ErrorLogger        *log.Logger
+	OutputLogger       *log.Logger    // Task STDOUT. Defaults to Logger
+	OutputErrorLogger  *log.Logger    // Task STDERR. Defaults to ErrorLogger
+	OutputContinuation *regexp.Regexp // Output lines matching are merged into the previous line's record
+	OnEvent            func(Event)    // Called when the task finishes
+	Retries            int            // Times a failed task is run again
+	RetryDelay         time.Duration  // Delay before the first retry, doubling each time
+	MaxRetryDelay      time.Duration  // Caps the retry delay. Zero for no limit
+	SuccessExitCodes   []int          // Exit codes that mean the task succeeded. Default 0
+}
+
+// succeeded returns true if the exit code means success.
+func (c TaskConfig) succeeded(exitCode int) bool {
+	if len(c.SuccessExitCodes) == 0 {
+		return exitCode == 0
+	}
+	for _, code := range c.SuccessExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
 }
 
 type ScheduleTaskConfig struct {
@@ -53,22 +87,90 @@ type ScheduleTaskConfig struct {
 }
 
 type ServiceConfig struct {
-	Path             string
-	Args             []string
-	StartupDelay     time.Duration
-	GracefulShutDown time.Duration
-	Logger           *log.Logger
-	ErrorLogger      *log.Logger
-	CrashConfig      CrashConfig
-	MonitorConfig    MonitorConfig
+	Path               string
+	Args               []string
+	StartupDelay       time.Duration
+	GracefulShutDown   time.Duration
+	StopFile           string // Created by the service to request its own clean restart
+	Logger             *log.Logger
+	ErrorLogger        *log.Logger
+	OutputLogger       *log.Logger         // Service STDOUT. Defaults to Logger
+	OutputErrorLogger  *log.Logger         // Service STDERR. Defaults to ErrorLogger
+	OutputContinuation *regexp.Regexp      // Output lines matching are merged into the previous line's record
+	OutputBuffer       *logging.RingBuffer // Recent output, for the logs command and crash reports
+	OnEvent            func(Event)         // Called when the service starts, stops or restarts, and on monitor pings
+	CrashConfig        CrashConfig
+	MonitorConfig      MonitorConfig   // A single monitor, checked along with Monitors
+	Monitors           []MonitorConfig // Checked according to MonitorPolicy
+	MonitorPolicy      string          // MonitorPolicyAny (default) or MonitorPolicyAll
+	MonitorFlap        FlapConfig
+	HangDiagnostics    DiagnosticsConfig // Gathered before the monitors restart the service
+}
+
+// Flap actions: what's done when the monitors restart a service too often
+const (
+	FlapActionAlert = "alert" // Log an error and raise EventMonitorFlapping (default)
+	FlapActionStop  = "stop"  // Also stop restarting the service, as if it exceeded its crash limit
+)
+
+// FlapConfig detects a service restarted by its monitors Count times within
+// Window.
+type FlapConfig struct {
+	Count  int
+	Window time.Duration
+	Action string
 }
 
 type CrashConfig struct {
 	MaxCountPerHour int
 	RestartDelay    time.Duration
+	Bundle          CrashBundleConfig
 }
 
+// ExecuteTask runs the task, retrying it if it fails. An error is returned if
+// the task couldn't be run, or its last run's exit code wasn't a success code.
+// A single event is raised once the task succeeds or runs out of retries, so
+// runs that a retry recovers from aren't reported as failures.
 func ExecuteTask(terminate chan struct{}, taskConf TaskConfig) (exitCode int, err error) {
+	taskName := exeName(taskConf.Path)
+	delay := taskConf.RetryDelay
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		var pid int
+		var execErr error
+		exitCode, pid, execErr = executeTaskOnce(terminate, taskConf)
+		err = execErr
+		if err == nil && !taskConf.succeeded(exitCode) {
+			err = fmt.Errorf("exited with code %d", exitCode)
+		}
+		stop := err == nil || attempt >= taskConf.Retries
+		if !stop {
+			logf(taskConf.Logger, logging.LevelWarn, taskName, "Task failed: %v. Retry %d of %d in %s", err, attempt+1, taskConf.Retries, delay)
+			select {
+			case <-terminate:
+				stop = true
+			case <-time.After(delay):
+			}
+		}
+		if stop {
+			event := Event{Type: EventTaskFinished, Name: taskName, PID: pid, ExitCode: exitCode,
+				Error: errorString(execErr), Duration: time.Since(start)}
+			if err != nil {
+				event.Type = EventTaskFailed
+			}
+			emit(taskConf.OnEvent, event)
+			return exitCode, err
+		}
+		delay *= 2
+		if taskConf.MaxRetryDelay > 0 && delay > taskConf.MaxRetryDelay {
+			delay = taskConf.MaxRetryDelay
+		}
+		// Startup delays only apply to the first run
+		taskConf.StartupDelay, taskConf.StartupRandomDelay = 0, 0
+	}
+}
+
+func executeTaskOnce(terminate chan struct{}, taskConf TaskConfig) (exitCode, pid int, err error) {
 	startupDelay := taskConf.StartupDelay
 	if taskConf.StartupRandomDelay > 0 {
 		startupDelay = startupDelay + time.Duration(random.Int63n(taskConf.StartupRandomDelay.Nanoseconds()))
@@ -86,83 +188,89 @@ func ExecuteTask(terminate chan struct{}, taskConf TaskConfig) (exitCode int, er
 		ExecTimeout:      taskConf.ExecTimeout,
 		GracefulShutDown: taskConf.GracefulShutDown,
 		StartupDelay:     startupDelay,
-		Stdout:           &logWriter{prefix: fmt.Sprintf("%s: STDOUT|", taskName), logger: taskConf.Logger},
-		Stderr:           &logWriter{prefix: fmt.Sprintf("%s: STDERR|", taskName), logger: taskConf.ErrorLogger},
 	}
+	stdout, stderr := outputWriters(&execConf, taskName,
+		outputLogger(taskConf.OutputLogger, taskConf.Logger),
+		outputLogger(taskConf.OutputErrorLogger, taskConf.ErrorLogger),
+		taskConf.OutputContinuation, nil)
 
 	executable := procmngt.NewExecutable(execConf)
 	if execConf.StartupDelay > 0 {
-		logf(taskConf.Logger, taskName, "Starting task (delayed: %s, timeout: %s)", execConf.StartupDelay, execConf.ExecTimeout)
+		logf(taskConf.Logger, logging.LevelInfo, taskName, "Starting task (delayed: %s, timeout: %s)", execConf.StartupDelay, execConf.ExecTimeout)
 	} else {
-		logf(taskConf.Logger, taskName, "Starting task (timeout: %s)", execConf.ExecTimeout)
+		logf(taskConf.Logger, logging.LevelInfo, taskName, "Starting task (timeout: %s)", execConf.ExecTimeout)
 	}
 	exitCode, err = executable.Execute(terminate)
-	logf(taskConf.Logger, taskName, "Task Stopped..., exit code %d, err %v", exitCode, err)
-	return exitCode, err
+	stdout.Flush()
+	stderr.Flush()
+	logf(taskConf.Logger, logging.LevelInfo, taskName, "Task Stopped..., exit code %d, err %v", exitCode, err)
+	return exitCode, stdout.getPID(), err
 }
 
 func exeName(path string) string {
 	return filepath.Base(path)
 }
 
-func logf(l *log.Logger, exeName string, format string, v ...interface{}) {
+func logf(l *log.Logger, level string, exeName string, format string, v ...interface{}) {
 	if l != nil {
-		l.Printf("%s: %s", exeName, fmt.Sprintf(format, v...))
+		logging.Output(l, logging.Record{Level: level, Source: exeName, Message: fmt.Sprintf(format, v...)})
 	}
 }
 
-type logWriter struct {
-	logger *log.Logger
-	prefix string
-	buf    bytes.Buffer
-}
-
-func (l *logWriter) Write(p []byte) (int, error) {
-	if l.logger == nil {
-		return len(p), nil
+func outputLogger(logger, fallback *log.Logger) *log.Logger {
+	if logger != nil {
+		return logger
 	}
-	// Write lines that we can find, otherwise leave in buffer
-	l.buf.Write(p)
+	return fallback
+}
 
-	scanner := bufio.NewScanner(&l.buf)
-	for scanner.Scan() {
-		l.logger.Printf("%s%s", l.prefix, scanner.Text())
+// outputWriters sets up the child process STDOUT and STDERR to log to the given loggers.
+func outputWriters(execConf *procmngt.ExecConfig, name string, logger, errorLogger *log.Logger,
+	continuation *regexp.Regexp, buffer *logging.RingBuffer) (stdout, stderr *logWriter) {
+	stdout = &logWriter{source: name, stream: "stdout", level: logging.LevelInfo, logger: logger, continuation: continuation, buffer: buffer}
+	stderr = &logWriter{source: name, stream: "stderr", level: logging.LevelError, logger: errorLogger, continuation: continuation, buffer: buffer}
+	execConf.Stdout = stdout
+	execConf.Stderr = stderr
+	execConf.OnStart = func(pid int) {
+		stdout.setPID(pid)
+		stderr.setPID(pid)
 	}
-	return len(p), nil
+	return stdout, stderr
 }
 
 func ExecuteService(terminate chan struct{}, svcConfig ServiceConfig) error {
 	serviceName := exeName(svcConfig.Path)
-	crashHandlingExec := &crashHandlingExecutable{serviceName: serviceName, svcConfig: svcConfig}
+	monitors := &monitorGroup{policy: svcConfig.MonitorPolicy, logger: svcConfig.Logger, serviceName: serviceName}
+	for _, mc := range append([]MonitorConfig{svcConfig.MonitorConfig}, svcConfig.Monitors...) {
+		if mc.enabled() {
+			monitors.monitors = append(monitors.monitors, &serviceMonitor{
+				serviceName: serviceName,
+				config:      mc,
+				logger:      svcConfig.Logger,
+				onEvent:     svcConfig.OnEvent,
+			})
+		}
+	}
+	if len(monitors.monitors) > 0 {
+		var urls []string
+		for _, sm := range monitors.monitors {
+			urls = append(urls, sm.config.URL)
+		}
+		logf(svcConfig.Logger, logging.LevelInfo, serviceName, "Starting service with monitor %s", strings.Join(urls, ", "))
+	}
+	crashHandlingExec := &crashHandlingExecutable{serviceName: serviceName, svcConfig: svcConfig, monitors: monitors}
 	go func() {
 		<-terminate
-		logf(svcConfig.Logger, serviceName, "Stopping service...")
+		logf(svcConfig.Logger, logging.LevelInfo, serviceName, "Stopping service...")
 	}()
-	t := terminate
-	if svcConfig.MonitorConfig.URL != "" && svcConfig.MonitorConfig.Interval > 0 {
-		t = make(chan struct{})
-		// Wrap our terminate channel in a monitor
-		logf(svcConfig.Logger, serviceName, "Starting service with monitor %s", svcConfig.MonitorConfig.URL)
-		monitor := &serviceMonitor{
-			serviceName: serviceName,
-			config:      svcConfig.MonitorConfig,
-			logger:      svcConfig.Logger,
-		}
-		go func() {
-			select {
-			case <-terminate:
-			case <-monitor.start(terminate):
-			}
-			close(t)
-		}()
-	}
-	_, err := crashHandlingExec.Executable(t)
+	_, err := crashHandlingExec.Executable(terminate)
 	return err
 }
 
 type crashHandlingExecutable struct {
 	svcConfig   ServiceConfig
 	serviceName string
+	monitors    *monitorGroup
 }
 
 func (che *crashHandlingExecutable) Executable(terminate chan struct{}) (exitCode int, err error) {
@@ -170,6 +278,12 @@ func (che *crashHandlingExecutable) Executable(terminate chan struct{}) (exitCod
 	max := che.svcConfig.CrashConfig.MaxCountPerHour
 	restartDelay := che.svcConfig.CrashConfig.RestartDelay
 	start := time.Now()
+	stopFile := che.svcConfig.StopFile
+	buffer := che.svcConfig.OutputBuffer
+	if buffer == nil {
+		buffer = logging.NewRingBuffer(crashOutputLines)
+	}
+	flaps := &flapDetector{config: che.svcConfig.MonitorFlap}
 restartLoop:
 	for {
 		execConf := procmngt.ExecConfig{
@@ -177,19 +291,95 @@ restartLoop:
 			Args:             che.svcConfig.Args,
 			GracefulShutDown: che.svcConfig.GracefulShutDown,
 			StartupDelay:     che.svcConfig.StartupDelay,
-			Stdout:           &logWriter{prefix: fmt.Sprintf("%s: STDOUT|", che.serviceName), logger: che.svcConfig.Logger},
-			Stderr:           &logWriter{prefix: fmt.Sprintf("%s: STDERR|", che.serviceName), logger: che.svcConfig.ErrorLogger},
+		}
+		stdout, stderr := outputWriters(&execConf, che.serviceName,
+			outputLogger(che.svcConfig.OutputLogger, che.svcConfig.Logger),
+			outputLogger(che.svcConfig.OutputErrorLogger, che.svcConfig.ErrorLogger),
+			che.svcConfig.OutputContinuation, buffer)
+		run := newServiceRun(terminate)
+		if stopFile != "" {
+			_ = os.Remove(stopFile)
+			execConf.Env = append(os.Environ(), stopFileEnvVar+"="+stopFile)
+			go watchStopFile(stopFile, run)
+		}
+		onStart := execConf.OnStart
+		execConf.OnStart = func(pid int) {
+			onStart(pid)
+			emit(che.svcConfig.OnEvent, Event{Type: EventServiceStarted, Name: che.serviceName, PID: pid})
+		}
+		var exitState *os.ProcessState
+		execConf.OnExit = func(state *os.ProcessState) {
+			exitState = state
+		}
+		if che.monitors != nil && len(che.monitors.monitors) > 0 {
+			// The monitors restart the service in place
+			failed := che.monitors.start(run.done)
+			go func() {
+				select {
+				case <-failed:
+					if che.svcConfig.HangDiagnostics.Action != "" {
+						che.logHangDiagnostics(stdout.getPID(), buffer, run.done)
+					}
+					run.restart(RestartReasonMonitor)
+				case <-run.done:
+				}
+			}()
 		}
 		executable := procmngt.NewExecutable(execConf)
+		runStart := time.Now()
 		if execConf.StartupDelay > 0 {
-			logf(che.svcConfig.Logger, che.serviceName, "Starting service (delayed %s)", execConf.StartupDelay)
+			logf(che.svcConfig.Logger, logging.LevelInfo, che.serviceName, "Starting service (delayed %s)", execConf.StartupDelay)
 		} else {
-			logf(che.svcConfig.Logger, che.serviceName, "Starting service...")
+			logf(che.svcConfig.Logger, logging.LevelInfo, che.serviceName, "Starting service...")
 		}
-		if exitCode, err = executable.Execute(terminate); err != nil {
-			logf(che.svcConfig.ErrorLogger, che.serviceName, "Service returned error: %v", err)
+		exitCode, err = executable.Execute(run.terminate)
+		stdout.Flush()
+		stderr.Flush()
+		run.finish()
+		if err != nil {
+			logf(che.svcConfig.ErrorLogger, logging.LevelError, che.serviceName, "Service returned error: %v", err)
 		} else {
-			logf(che.svcConfig.Logger, che.serviceName, "Service stopped with exit code %d", exitCode)
+			logf(che.svcConfig.Logger, logging.LevelInfo, che.serviceName, "Service stopped with exit code %d", exitCode)
+		}
+		emit(che.svcConfig.OnEvent, Event{Type: EventServiceStopped, Name: che.serviceName, PID: stdout.getPID(),
+			ExitCode: exitCode, Error: errorString(err)})
+
+		if reason := run.restartReason(); reason != "" && !IsClosed(terminate) {
+			if reason == RestartReasonMonitor {
+				if msg := flaps.restarted(time.Now()); msg != "" {
+					logf(che.svcConfig.ErrorLogger, logging.LevelError, che.serviceName, "Service flapping: %s", msg)
+					emit(che.svcConfig.OnEvent, Event{Type: EventMonitorFlapping, Name: che.serviceName, Reason: flaps.action(), Error: msg})
+					if flaps.action() == FlapActionStop {
+						err = errors.New("Service flapping: " + msg)
+						break restartLoop
+					}
+				}
+			}
+			// A requested restart is not a crash, so restart straight away
+			logf(che.svcConfig.Logger, logging.LevelInfo, che.serviceName, "Restarting service (%s)", reason)
+			emit(che.svcConfig.OnEvent, Event{Type: EventServiceRestarted, Name: che.serviceName, Reason: reason})
+			continue
+		}
+		if !IsClosed(terminate) {
+			emit(che.svcConfig.OnEvent, Event{Type: EventServiceCrashed, Name: che.serviceName, PID: stdout.getPID(),
+				ExitCode: exitCode, Error: errorString(err)})
+			output := lastOutput(buffer, runStart)
+			che.logLastOutput(output)
+			if che.svcConfig.CrashConfig.Bundle.Dir != "" {
+				env := execConf.Env
+				if env == nil {
+					env = os.Environ()
+				}
+				go che.crashBundle(crashDetails{
+					exitCode:  exitCode,
+					exitState: exitState,
+					err:       err,
+					pid:       stdout.getPID(),
+					started:   runStart,
+					env:       env,
+					output:    output,
+				})
+			}
 		}
 
 		// Increment resetting every hour
@@ -200,6 +390,7 @@ restartLoop:
 		}
 		if max > 1 && crashCount >= max {
 			err = errors.New("Max crash count exceeded.")
+			emit(che.svcConfig.OnEvent, Event{Type: EventCrashLimitExceeded, Name: che.serviceName, ExitCode: exitCode, Error: err.Error()})
 			break restartLoop
 		}
 		// Ensure we've got at least a small delay so we act on terminate first
@@ -211,7 +402,179 @@ restartLoop:
 			break restartLoop
 		case <-time.After(restartDelay):
 		}
-		logf(che.svcConfig.ErrorLogger, che.serviceName, "Restarting service (crash count: %d)", crashCount)
+		logf(che.svcConfig.ErrorLogger, logging.LevelWarn, che.serviceName, "Restarting service (crash count: %d)", crashCount)
+		emit(che.svcConfig.OnEvent, Event{Type: EventServiceRestarted, Name: che.serviceName, Reason: RestartReasonCrash})
 	}
 	return exitCode, err
 }
+
+func (che *crashHandlingExecutable) logHangDiagnostics(pid int, buffer *logging.RingBuffer, exited chan struct{}) {
+	logf(che.svcConfig.Logger, logging.LevelInfo, che.serviceName, "Gathering hang diagnostics (%s)", che.svcConfig.HangDiagnostics.Action)
+	name, err := che.hangDiagnostics(pid, buffer, exited)
+	if err != nil {
+		logf(che.svcConfig.ErrorLogger, logging.LevelError, che.serviceName, "Unable to gather hang diagnostics: %v", err)
+		if name == "" {
+			return
+		}
+	}
+	logf(che.svcConfig.Logger, logging.LevelInfo, che.serviceName, "Hang diagnostics written to %s", name)
+}
+
+// flapDetector tracks the times the monitors restarted a service.
+type flapDetector struct {
+	config   FlapConfig
+	restarts []time.Time
+}
+
+// restarted records a monitor restart, returning a description if the
+// service is flapping. The count starts again after each detection so the
+// action isn't repeated on every restart.
+func (f *flapDetector) restarted(now time.Time) string {
+	if f.config.Count <= 0 {
+		return ""
+	}
+	recent := f.restarts[:0]
+	for _, t := range f.restarts {
+		if now.Sub(t) < f.config.Window {
+			recent = append(recent, t)
+		}
+	}
+	f.restarts = append(recent, now)
+	if len(f.restarts) < f.config.Count {
+		return ""
+	}
+	f.restarts = nil
+	return fmt.Sprintf("Restarted by its monitors %d times within %s", f.config.Count, f.config.Window)
+}
+
+func (f *flapDetector) action() string {
+	if f.config.Action == "" {
+		return FlapActionAlert
+	}
+	return f.config.Action
+}
+
+// lastOutput returns the service's last lines of output from the run started at since.
+func lastOutput(buffer *logging.RingBuffer, since time.Time) []logging.Record {
+	var lines []logging.Record
+	for _, r := range buffer.Last(crashOutputLines) {
+		if !r.Time.Before(since) {
+			lines = append(lines, r)
+		}
+	}
+	return lines
+}
+
+// logLastOutput logs the last lines of output to help diagnose why the service stopped.
+func (che *crashHandlingExecutable) logLastOutput(lines []logging.Record) {
+	if len(lines) == 0 {
+		return
+	}
+	logf(che.svcConfig.ErrorLogger, logging.LevelError, che.serviceName, "Service stopped unexpectedly. Last %d lines of output:", len(lines))
+	for _, r := range lines {
+		logf(che.svcConfig.ErrorLogger, logging.LevelError, che.serviceName, "> %s|%s", strings.ToUpper(r.Stream), r.Message)
+	}
+}
+
+// crashBundle writes the crash bundle in the background so the restart isn't
+// held up by large crash files.
+func (che *crashHandlingExecutable) crashBundle(details crashDetails) {
+	name, err := che.writeCrashBundle(details)
+	if err != nil {
+		logf(che.svcConfig.ErrorLogger, logging.LevelError, che.serviceName, "Unable to write crash bundle: %v", err)
+		return
+	}
+	logf(che.svcConfig.Logger, logging.LevelInfo, che.serviceName, "Crash bundle written to %s", name)
+}
+
+// serviceRun is the terminate channel for a single run of a service process.
+// It's closed when the service is terminated or when a restart is requested.
+type serviceRun struct {
+	terminate chan struct{}
+	done      chan struct{}
+	once      sync.Once
+	mu        sync.Mutex
+	reason    string
+}
+
+func newServiceRun(terminate chan struct{}) *serviceRun {
+	run := &serviceRun{
+		terminate: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-terminate:
+			run.once.Do(func() { close(run.terminate) })
+		case <-run.done:
+		}
+	}()
+	return run
+}
+
+// restart stops this run of the service so it's restarted.
+func (run *serviceRun) restart(reason string) {
+	run.once.Do(func() {
+		run.mu.Lock()
+		run.reason = reason
+		run.mu.Unlock()
+		close(run.terminate)
+	})
+}
+
+func (run *serviceRun) restartReason() string {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return run.reason
+}
+
+// finish must be called once the process has exited.
+func (run *serviceRun) finish() {
+	close(run.done)
+}
+
+// watchStopFile restarts the run if the service creates its stop file.
+func watchStopFile(stopFile string, run *serviceRun) {
+	watcher := fswatch.New([]string{stopFile}, stopFilePoll)
+	defer watcher.Close()
+	for {
+		if osutils.FileExists(stopFile) {
+			_ = os.Remove(stopFile)
+			run.restart("stop file requested restart")
+			return
+		}
+		select {
+		case <-watcher.Events:
+		case <-run.done:
+			return
+		}
+	}
+}
+
+// IsClosed reports whether c has been closed, without blocking.
+func IsClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// LimitedBuffer keeps the first Max bytes written to it and discards the rest,
+// so a chatty command's output can be captured without unbounded memory.
+type LimitedBuffer struct {
+	bytes.Buffer
+	Max int
+}
+
+func (b *LimitedBuffer) Write(p []byte) (int, error) {
+	if room := b.Max - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
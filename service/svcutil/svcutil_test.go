@@ -8,6 +8,7 @@
 package svcutil_test
 
 import (
+	"archive/zip"
 	"bytes"
 	"io/ioutil"
 	"log"
@@ -18,9 +19,12 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/papercutsoftware/silver/lib/logging"
+	"github.com/papercutsoftware/silver/lib/osutils"
 	"github.com/papercutsoftware/silver/service/svcutil"
 )
 
@@ -215,6 +219,215 @@ func Test_ExecuteTask_ConsoleLogger(t *testing.T) {
 	}
 }
 
+func Test_ExecuteTask_OutputLogger(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeHelloWorldExe(t)
+	defer os.RemoveAll(tmpDir)
+
+	var logBuf bytes.Buffer
+	var outputBuf bytes.Buffer
+
+	taskConf := svcutil.TaskConfig{
+		Path:              testExe,
+		Logger:            log.New(&logBuf, "", 0),
+		OutputLogger:      log.New(&outputBuf, "", 0),
+		OutputErrorLogger: log.New(&outputBuf, "", 0),
+	}
+
+	// Act
+	svcutil.ExecuteTask(nil, taskConf)
+
+	// Assert
+	output := outputBuf.String()
+	if !strings.Contains(output, "STDOUT|Hello World") {
+		t.Errorf("Expected task output in the output log: %s", output)
+	}
+	if strings.Contains(output, "Starting task") {
+		t.Errorf("Did not expect Silver's messages in the output log: %s", output)
+	}
+
+	main := logBuf.String()
+	if !strings.Contains(main, "Starting task") {
+		t.Errorf("Expected Silver's messages in the main log: %s", main)
+	}
+	if strings.Contains(main, "Hello World") {
+		t.Errorf("Did not expect task output in the main log: %s", main)
+	}
+}
+
+func Test_ExecuteTask_OutputLines(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeOutputLinesExe(t)
+	defer os.RemoveAll(tmpDir)
+
+	var outputBuf bytes.Buffer
+	taskConf := svcutil.TaskConfig{
+		Path:               testExe,
+		OutputLogger:       log.New(&outputBuf, "", 0),
+		OutputContinuation: regexp.MustCompile(`^\s+at `),
+	}
+
+	// Act
+	svcutil.ExecuteTask(nil, taskConf)
+
+	// Assert
+	output := outputBuf.String()
+	if !strings.Contains(output, "STDOUT|Hello World\n") {
+		t.Errorf("Expected the partly written line as one line: %s", output)
+	}
+	if !strings.Contains(output, "STDOUT|Exception in thread main\n\tat com.example.Main.run(Main.java:10)\n\tat com.example.Main.main") {
+		t.Errorf("Expected the stack trace merged into one record: %s", output)
+	}
+	if strings.Count(output, "STDOUT|xxx") != 2 {
+		t.Errorf("Expected the long line to be split in two")
+	}
+	if !strings.HasSuffix(output, "STDOUT|Goodbye\n") {
+		t.Errorf("Expected the final partial line to be flushed on exit: %s", output[len(output)-50:])
+	}
+}
+
+func Test_ExecuteTask_OutputRedacted(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeHelloWorldExe(t)
+	defer os.RemoveAll(tmpDir)
+	redactor, _ := logging.NewRedactor([]string{"World"}, "")
+	logging.SetRedactor(redactor)
+	defer logging.SetRedactor(nil)
+
+	var outputBuf bytes.Buffer
+	taskConf := svcutil.TaskConfig{
+		Path:         testExe,
+		OutputLogger: log.New(&outputBuf, "", 0),
+	}
+
+	// Act
+	svcutil.ExecuteTask(nil, taskConf)
+
+	// Assert
+	output := outputBuf.String()
+	if !strings.Contains(output, "STDOUT|Hello [REDACTED]") {
+		t.Errorf("Expected redacted task output: %s", output)
+	}
+}
+
+func Test_ExecuteService_Crash_LogsLastOutput(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeCrashExe(t)
+	defer os.RemoveAll(tmpDir)
+
+	var logBuf, errorBuf bytes.Buffer
+	buffer := logging.NewRingBuffer(100)
+	serviceConf := svcutil.ServiceConfig{
+		Path:         testExe,
+		Logger:       log.New(&logBuf, "", 0),
+		ErrorLogger:  log.New(&errorBuf, "", 0),
+		OutputBuffer: buffer,
+		CrashConfig: svcutil.CrashConfig{
+			MaxCountPerHour: 2,
+		},
+	}
+
+	// Act
+	svcutil.ExecuteService(make(chan struct{}), serviceConf)
+
+	// Assert
+	errors := errorBuf.String()
+	if strings.Count(errors, "Service stopped unexpectedly. Last 1 lines of output:") != 2 ||
+		!strings.Contains(errors, "> STDOUT|CRASHED!") {
+		t.Errorf("Expected the last output to be logged on each crash: %s", errors)
+	}
+	if len(buffer.Last(0)) != 2 {
+		t.Errorf("Expected the output of both runs in the buffer")
+	}
+}
+
+func Test_ExecuteService_Crash_WritesBundle(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeCrashExe(t)
+	defer os.RemoveAll(tmpDir)
+	crashDir := filepath.Join(tmpDir, "crashes")
+	os.Setenv("TEST_API_TOKEN", "secret-value")
+	defer os.Unsetenv("TEST_API_TOKEN")
+	// Other files in the directory, which must not be pruned, and an old bundle which is
+	service := strings.TrimSuffix(filepath.Base(testExe), ".exe")
+	os.MkdirAll(crashDir, 0755)
+	unrelated := []string{filepath.Join(crashDir, "update-1.2.zip"), filepath.Join(crashDir, "other-20200101-000000.zip")}
+	oldBundle := filepath.Join(crashDir, service+"-20200101-000000.zip")
+	for _, f := range append(unrelated, oldBundle) {
+		ioutil.WriteFile(f, nil, 0644)
+		os.Chtimes(f, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+	}
+	// Crash files written during the run, one over the size limit
+	smallFile, largeFile := filepath.Join(tmpDir, "small.dump"), filepath.Join(tmpDir, "large.dump")
+	ioutil.WriteFile(smallFile, []byte("small"), 0644)
+	ioutil.WriteFile(largeFile, bytes.Repeat([]byte("x"), 100), 0644)
+	for _, f := range []string{smallFile, largeFile} {
+		os.Chtimes(f, time.Now().Add(time.Hour), time.Now().Add(time.Hour))
+	}
+
+	serviceConf := svcutil.ServiceConfig{
+		Path:   testExe,
+		Logger: log.New(ioutil.Discard, "", 0),
+		CrashConfig: svcutil.CrashConfig{
+			MaxCountPerHour: 2,
+			Bundle: svcutil.CrashBundleConfig{
+				Dir:         crashDir,
+				Files:       []string{filepath.Join(tmpDir, "*.dump")},
+				MaxCount:    1,
+				MaxFileSize: 10,
+				Version:     "1.2.3",
+			},
+		},
+	}
+
+	// Act
+	svcutil.ExecuteService(make(chan struct{}), serviceConf)
+
+	// Assert - bundles are written in the background
+	var bundles []string
+	for i := 0; i < 50 && osutils.FileExists(oldBundle); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	bundles, _ = filepath.Glob(filepath.Join(crashDir, service+"-*.zip"))
+	if len(bundles) != 1 || bundles[0] == oldBundle {
+		t.Fatalf("Expected one new crash bundle (with retention of 1), got %v", bundles)
+	}
+	for _, f := range unrelated {
+		if !osutils.FileExists(f) {
+			t.Errorf("Expected %s to be left alone", f)
+		}
+	}
+	zr, err := zip.OpenReader(bundles[0])
+	if err != nil {
+		t.Fatalf("Invalid crash bundle: %v", err)
+	}
+	defer zr.Close()
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		r, _ := f.Open()
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		contents[f.Name] = string(b)
+	}
+	report := contents["crash.json"]
+	if !strings.Contains(report, `"ExitCode": 1`) || !strings.Contains(report, `"Version": "1.2.3"`) ||
+		!strings.Contains(report, `"SHA256"`) {
+		t.Errorf("Unexpected crash report: %s", report)
+	}
+	if strings.Contains(report, "secret-value") || !strings.Contains(report, "TEST_API_TOKEN=[REDACTED]") {
+		t.Errorf("Expected sensitive environment variables to be redacted")
+	}
+	if !strings.Contains(contents["output.log"], "STDOUT|CRASHED!") {
+		t.Errorf("Expected the last output in the bundle: %s", contents["output.log"])
+	}
+	if _, ok := contents["files/small.dump"]; !ok {
+		t.Errorf("Expected the crash file in the bundle")
+	}
+	if _, ok := contents["files/large.dump"]; ok || !strings.Contains(report, "large.dump (100 bytes)") {
+		t.Errorf("Expected the large crash file listed but not included: %s", report)
+	}
+}
+
 func Test_ExecuteService_CrashConfig_RestartDelay(t *testing.T) {
 	// Arrange
 	const shutdownIn = 3 * time.Second
@@ -286,6 +499,178 @@ func Test_ExecuteService_CrashConfig_MaxCount(t *testing.T) {
 	}
 }
 
+func Test_ExecuteService_StopFile_RequestsRestart(t *testing.T) {
+	// Arrange
+	const shutdownIn = 3 * time.Second
+	tmpDir, testExe := makeRequestRestartExe(t)
+	defer os.RemoveAll(tmpDir)
+
+	var logBuf bytes.Buffer
+
+	serviceConf := svcutil.ServiceConfig{
+		Path:             testExe,
+		StopFile:         filepath.Join(tmpDir, ".stop-service"),
+		GracefulShutDown: 2 * time.Second,
+		Logger:           log.New(&logBuf, "", 0),
+		CrashConfig: svcutil.CrashConfig{
+			RestartDelay: 10 * time.Second,
+		},
+	}
+
+	terminate := make(chan struct{})
+
+	// Act
+	go func() {
+		time.Sleep(shutdownIn)
+		close(terminate)
+	}()
+	svcutil.ExecuteService(terminate, serviceConf)
+
+	// Assert
+	output := logBuf.String()
+	// The crash restart delay would only allow one start in our window
+	startedTimes := len(regexp.MustCompile("STARTED").FindAllString(output, -1))
+	if startedTimes < 2 {
+		t.Errorf("Expected the service to restart straight away.  Started: %v\n%s", startedTimes, output)
+	}
+	if !strings.Contains(output, "stop file requested restart") {
+		t.Errorf("Expected restart reason in logging output: %s", output)
+	}
+}
+
+func Test_ExecuteService_Events(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeCrashExe(t)
+	defer os.RemoveAll(tmpDir)
+
+	var eventsLock sync.Mutex
+	var events []string
+	serviceConf := svcutil.ServiceConfig{
+		Path: testExe,
+		CrashConfig: svcutil.CrashConfig{
+			MaxCountPerHour: 2,
+		},
+		OnEvent: func(e svcutil.Event) {
+			eventsLock.Lock()
+			events = append(events, e.Type)
+			eventsLock.Unlock()
+		},
+	}
+
+	// Act
+	_ = svcutil.ExecuteService(nil, serviceConf)
+
+	// Assert
+	eventsLock.Lock()
+	defer eventsLock.Unlock()
+	expected := []string{
+		svcutil.EventServiceStarted, svcutil.EventServiceStopped, svcutil.EventServiceCrashed, svcutil.EventServiceRestarted,
+		svcutil.EventServiceStarted, svcutil.EventServiceStopped, svcutil.EventServiceCrashed, svcutil.EventCrashLimitExceeded,
+	}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+}
+
+func Test_ExecuteTask_Event(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeHelloWorldExe(t)
+	defer os.RemoveAll(tmpDir)
+	var event svcutil.Event
+	taskConf := svcutil.TaskConfig{
+		Path:    testExe,
+		OnEvent: func(e svcutil.Event) { event = e },
+	}
+
+	// Act
+	svcutil.ExecuteTask(nil, taskConf)
+
+	// Assert
+	if event.Type != svcutil.EventTaskFinished {
+		t.Errorf("Expected %s event, got '%s'", svcutil.EventTaskFinished, event.Type)
+	}
+	if event.Name != filepath.Base(testExe) || event.Duration <= 0 || event.PID == 0 {
+		t.Errorf("Unexpected event details: %+v", event)
+	}
+}
+
+func Test_ExecuteTask_Retries(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeCrashExe(t)
+	defer os.RemoveAll(tmpDir)
+	var events []string
+	taskConf := svcutil.TaskConfig{
+		Path:       testExe,
+		Retries:    2,
+		RetryDelay: 10 * time.Millisecond,
+		OnEvent:    func(e svcutil.Event) { events = append(events, e.Type) },
+	}
+	start := time.Now()
+
+	// Act
+	exitCode, err := svcutil.ExecuteTask(nil, taskConf)
+
+	// Assert: reported once, after the last retry
+	if exitCode != 1 || err == nil {
+		t.Errorf("Expected exit code 1 and an error, got %d, %v", exitCode, err)
+	}
+	if strings.Join(events, ",") != svcutil.EventTaskFailed {
+		t.Errorf("Expected a single %s event, got %v", svcutil.EventTaskFailed, events)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected 3 runs of the task, took only %s", elapsed)
+	}
+}
+
+func Test_ExecuteTask_RetrySucceeds_NoFailedEvent(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeFailOnceExe(t)
+	defer os.RemoveAll(tmpDir)
+	var events []string
+	taskConf := svcutil.TaskConfig{
+		Path:       testExe,
+		Args:       []string{filepath.Join(tmpDir, "failed-once")},
+		Retries:    2,
+		RetryDelay: 10 * time.Millisecond,
+		OnEvent:    func(e svcutil.Event) { events = append(events, e.Type) },
+	}
+
+	// Act
+	exitCode, err := svcutil.ExecuteTask(nil, taskConf)
+
+	// Assert
+	if exitCode != 0 || err != nil {
+		t.Errorf("Expected the retry to succeed, got %d, %v", exitCode, err)
+	}
+	if strings.Join(events, ",") != svcutil.EventTaskFinished {
+		t.Errorf("Expected a single %s event, got %v", svcutil.EventTaskFinished, events)
+	}
+}
+
+func Test_ExecuteTask_SuccessExitCodes(t *testing.T) {
+	// Arrange
+	tmpDir, testExe := makeCrashExe(t)
+	defer os.RemoveAll(tmpDir)
+	var events []string
+	taskConf := svcutil.TaskConfig{
+		Path:             testExe,
+		Retries:          2,
+		SuccessExitCodes: []int{0, 1},
+		OnEvent:          func(e svcutil.Event) { events = append(events, e.Type) },
+	}
+
+	// Act
+	exitCode, err := svcutil.ExecuteTask(nil, taskConf)
+
+	// Assert
+	if exitCode != 1 || err != nil {
+		t.Errorf("Expected exit code 1 to succeed, got %d, %v", exitCode, err)
+	}
+	if strings.Join(events, ",") != svcutil.EventTaskFinished {
+		t.Errorf("Expected a single %s event, got %v", svcutil.EventTaskFinished, events)
+	}
+}
+
 func makeHelloWorldExe(t *testing.T) (tmpDir, testExe string) {
 	_, thisFile, _, _ := runtime.Caller(0)
 	src := path.Dir(thisFile) + "/testexes/helloworld.go"
@@ -310,6 +695,24 @@ func makeCrashExe(t *testing.T) (tmpDir, testExe string) {
 	return makeTestExe(t, src)
 }
 
+func makeFailOnceExe(t *testing.T) (tmpDir, testExe string) {
+	_, thisFile, _, _ := runtime.Caller(0)
+	src := path.Dir(thisFile) + "/testexes/fail-once.go"
+	return makeTestExe(t, src)
+}
+
+func makeRequestRestartExe(t *testing.T) (tmpDir, testExe string) {
+	_, thisFile, _, _ := runtime.Caller(0)
+	src := path.Dir(thisFile) + "/testexes/request-restart.go"
+	return makeTestExe(t, src)
+}
+
+func makeOutputLinesExe(t *testing.T) (tmpDir, testExe string) {
+	_, thisFile, _, _ := runtime.Caller(0)
+	src := path.Dir(thisFile) + "/testexes/output-lines.go"
+	return makeTestExe(t, src)
+}
+
 func makeTestExe(t *testing.T, testSrc string) (tmpDir, testExe string) {
 	tmpDir, err := ioutil.TempDir("", "TestSvcutil")
 	if err != nil {
@@ -324,3 +727,20 @@ func makeTestExe(t *testing.T, testSrc string) (tmpDir, testExe string) {
 	}
 	return tmpDir, exe
 }
+
+func Test_LimitedBuffer_KeepsFirstMaxBytes(t *testing.T) {
+	// Arrange
+	b := &svcutil.LimitedBuffer{Max: 5}
+
+	// Act
+	n1, _ := b.Write([]byte("abc"))
+	n2, _ := b.Write([]byte("defgh"))
+
+	// Assert
+	if n1 != 3 || n2 != 5 {
+		t.Errorf("Expected writes to report all bytes written, got %d and %d", n1, n2)
+	}
+	if b.String() != "abcde" {
+		t.Errorf("Expected the first 5 bytes to be kept, got %q", b.String())
+	}
+}
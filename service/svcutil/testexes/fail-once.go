@@ -0,0 +1,28 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+// +build ignore
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Fails the first time it's run, creating the marker file given, and
+// succeeds once the marker exists.
+func main() {
+	marker := os.Args[1]
+	if _, err := os.Stat(marker); err == nil {
+		fmt.Println("SUCCEEDED")
+		return
+	}
+	_ = os.WriteFile(marker, nil, 0644)
+	fmt.Println("FAILED")
+	os.Exit(1)
+}
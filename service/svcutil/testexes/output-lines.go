@@ -0,0 +1,31 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+// +build ignore
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	// A line written in parts
+	fmt.Print("Hello ")
+	os.Stdout.Sync()
+	fmt.Println("World")
+	// A stack trace
+	fmt.Println("Exception in thread main")
+	fmt.Println("\tat com.example.Main.run(Main.java:10)")
+	fmt.Println("\tat com.example.Main.main(Main.java:5)")
+	// A line longer than the 64KB scanner limit
+	fmt.Println(strings.Repeat("x", 100*1024))
+	// A final line without a newline
+	fmt.Print("Goodbye")
+}
@@ -0,0 +1,34 @@
+// SILVER - Service Wrapper
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+// +build ignore
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	c := make(chan os.Signal, 10)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+
+	fmt.Println("STARTED")
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		// Ask Silver for a clean restart
+		_ = os.WriteFile(os.Getenv("SILVER_SERVICE_STOP_FILE"), nil, 0644)
+	}()
+
+	<-c
+	fmt.Println("Shutting down...")
+	os.Exit(0)
+}
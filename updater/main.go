@@ -23,6 +23,7 @@ import (
 
 var (
 	versionFile     = flag.String("f", ".version", "Set version file")
+	resultFile      = flag.String("r", update.ResultFileName, "Set file recording the result of the last update check")
 	showVersion     = flag.Bool("v", false, "Display current installed version and exit")
 	overrideVersion = flag.String("c", "", "Override current installed version")
 	httpProxy       = flag.String("p", "", "Set HTTP proxy in format http://server:port")
@@ -100,6 +101,7 @@ func main() {
 	}
 
 	ok, err := upgradeIfRequired(checkURL, *publicKey)
+	writeResult(ok, err)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
 		os.Exit(1)
@@ -110,3 +112,21 @@ func main() {
 		fmt.Printf("Upgrade successful at %s.\n", time.Now().Format(time.RFC822))
 	}
 }
+
+func writeResult(upgraded bool, err error) {
+	result := update.Result{
+		Time:    time.Now(),
+		Result:  update.ResultNoUpdate,
+		Version: update.ReadCurrentVersion(*versionFile),
+	}
+	switch {
+	case err != nil:
+		result.Result = update.ResultFailed
+		result.Error = err.Error()
+	case upgraded:
+		result.Result = update.ResultUpdated
+	}
+	if err := update.WriteResult(*resultFile, result); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "WARNING: Unable to write update result: %v\n", err)
+	}
+}
@@ -0,0 +1,61 @@
+// SILVER - Service Wrapper
+// Auto Updater
+//
+// Copyright (c) 2026 PaperCut Software http://www.papercut.com/
+// Use of this source code is governed by an MIT or GPL Version 2 license.
+// See the project's LICENSE file for more information.
+//
+
+package update
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// ResultFileName is where the outcome of the last update check is recorded,
+// so the service wrapper can report it.
+const ResultFileName = ".update-result.json"
+
+// Update check outcomes
+const (
+	ResultUpdated  = "updated"
+	ResultNoUpdate = "no-update"
+	ResultFailed   = "failed"
+)
+
+// Result is the outcome of an update check.
+type Result struct {
+	Time    time.Time
+	Result  string
+	Version string // Installed version after the check
+	Error   string `json:",omitempty"`
+}
+
+// WriteResult atomically replaces the result file.
+func WriteResult(file string, r Result) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// ReadResult reads the result file. It returns nil if no check has been
+// recorded.
+func ReadResult(file string) *Result {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	r := &Result{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil
+	}
+	return r
+}